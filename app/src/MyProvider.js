@@ -7,9 +7,10 @@ export default class MyProvider extends Component {
     state = {
     };
 
-    getLoginState = (email, validEmail) => {
+    getLoginState = (email, password, validEmail) => {
         const variables = {
-        email: email
+        email: email,
+        password: password
         };
         const query = LOGIN_USER;
         if (!validEmail) {
@@ -22,9 +23,10 @@ export default class MyProvider extends Component {
             }) // send post request to graphql endpoint with login query and variables
             .then(resp => {
             console.log("Graphql User response: ", resp.data);
-            const userData = resp.data.data.loginUser;
+            const { token, user } = resp.data.data.login;
             this.setState({
-                userData
+                token,
+                userData: user
             });
             });
         }
@@ -28,7 +28,7 @@ export default class LoginForm extends Component {
         <React.Fragment>
           <Form onSubmit={(e) => {
             e.preventDefault();
-            value(this.state.email, this.state.validEmail);
+            value(this.state.email, this.state.password, this.state.validEmail);
             }}>
             <Input
               type="text"
@@ -38,13 +38,13 @@ export default class LoginForm extends Component {
               onBlur={this.checkEmail}
               required
             />
-            {/* <input
-            type="password"
-            value={this.state.password}
-            onChange={event => this.setState({ password: event.target.value })}
-            placeholder="Password"
-            required
-          /> */}
+            <Input
+              type="password"
+              value={this.state.password}
+              onChange={event => this.setState({ password: event.target.value })}
+              placeholder="Password"
+              required
+            />
 
             <Button type="submit" primary>
               Login
@@ -1,16 +1,20 @@
 export const LOGIN_USER = `
-query loginUser($email: String!) {
-  loginUser(email: $email) {
-    id
-    name
-    email
-    profileImageURL
-    dogs {
+mutation login($email: String!, $password: String!) {
+  login(email: $email, password: $password) {
+    token
+    expiresAt
+    user {
       id
       name
-      age
-      breed
+      email
       profileImageURL
+      dogs {
+        id
+        name
+        age
+        breed
+        profileImageURL
+      }
     }
   }
 }`;
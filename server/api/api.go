@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/graph-gophers/graphql-go"
-	"github.com/minio/minio-go"
-	"github.com/raymondvooo/doggy-date-app/server/postgres"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/images"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"io"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -16,13 +19,8 @@ type Email struct {
 	Email string `json:"email"`
 }
 
-type ProfileBuilder struct {
-	ID       graphql.ID
-	ImageURL string
-}
-
 //CheckEmailExists checks against the database to see if email exists in the system
-func CheckEmailExists(w http.ResponseWriter, req *http.Request, db *postgres.Db) {
+func CheckEmailExists(w http.ResponseWriter, req *http.Request, db store.Store) {
 	var e Email
 	decoder := json.NewDecoder(req.Body)
 	err := decoder.Decode(&e)
@@ -46,51 +44,109 @@ func CheckEmailExists(w http.ResponseWriter, req *http.Request, db *postgres.Db)
 	}
 }
 
-// UploadAnyS3 upload any file to S3
-func (pb *ProfileBuilder) UploadAnyS3(w http.ResponseWriter, req *http.Request, minioClient *minio.Client, db *postgres.Db, tableType string, id graphql.ID) {
+// UploadProfileImage resizes a profile picture and saves every size to the configured image store
+func UploadProfileImage(w http.ResponseWriter, req *http.Request, imgStore storage.ImageStore, db store.Store, tableType string, id graphql.ID) {
+	// Only the owner of the user or dog may replace its picture
+	if err := auth.CanEdit(req.Context(), db, tableType, id); err != nil {
+		AuthError(w, err)
+		return
+	}
 	// Create context for cancel deadline signal
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	//Gets multipart file and header
+	//Gets multipart file and header, leaving room for the multipart framing around the image
+	req.Body = http.MaxBytesReader(w, req.Body, images.MaxBytes+1<<20)
 	file, header, err := req.FormFile("uploadfile")
 	if err != nil {
 		fmt.Println(err)
+		http.Error(w, "Bad request: Please provide an image in uploadfile", http.StatusBadRequest)
 		return
 	}
-	contentType := header.Header["Content-Type"][0]
-	contentDisposition := header.Header["Content-Disposition"][0]                                      // returns form-data
-	contentDisposition = "inline;" + contentDisposition[strings.IndexByte(contentDisposition, ';')+1:] //replace form-data with inline
-	fmt.Println("Content-Type ", contentType)
-	fmt.Println("Content-Disposition ", contentDisposition)
 	defer file.Close()
-	bucketName := "doggy-date-app"
-	path := "dogs/" //path to folder inside bucket
-	success, err := minioClient.PutObjectWithContext(ctx, bucketName, path+header.Filename, file, header.Size, minio.PutObjectOptions{
-		ContentType:        contentType,
-		ContentDisposition: contentDisposition,
-		// ContentType:        "application/octet-stream",
-		// ContentDisposition: "inline",
-	})
+	imgURL, err := images.Save(ctx, imgStore, db, tableType, id, file)
 	if err != nil {
 		fmt.Println(err)
+		ImageError(w, err)
 		return
 	}
-	imgURL := fmt.Sprintf("https://d2m79q3ctf5ck3.cloudfront.net/%s", header.Filename)
-	pb.UpdateProfilePic(db, tableType, id, imgURL)
-	fmt.Println("Successfully uploaded bytes: ", success)
+	fmt.Println("Successfully uploaded: ", header.Filename)
 	w.Write([]byte(imgURL))
 }
 
-// UpdateProfilePic updates profile picture row in postgres
-func (pb *ProfileBuilder) UpdateProfilePic(db *postgres.Db, tableType string, id graphql.ID, imgURL string) {
-	if _, err := db.UpdateProfilePic(tableType, id, imgURL); err != nil {
-		fmt.Println("UpdateProfilePic Err ", err)
+// ServeCertificate streams a vaccination certificate from the private document store,
+// only to the dog's owner
+func ServeCertificate(w http.ResponseWriter, req *http.Request, docs storage.ImageStore, db store.Store, dogID graphql.ID, id graphql.ID) {
+	if err := auth.CanEdit(req.Context(), db, "dogs", dogID); err != nil {
+		AuthError(w, err)
+		return
+	}
+	records, err := db.GetVaccinations([]graphql.ID{dogID})
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Error: could not load certificate", http.StatusInternalServerError)
+		return
+	}
+	var cert *types.Vaccination
+	for i, v := range records[dogID] {
+		if v.ID == id && v.CertificateKey != "" {
+			cert = &records[dogID][i]
+		}
+	}
+	if cert == nil {
+		http.Error(w, "Error: Certificate not found", http.StatusNotFound)
+		return
+	}
+	f, err := docs.Get(req.Context(), cert.CertificateKey)
+	if err == storage.ErrNotFound {
+		http.Error(w, "Error: Certificate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Error: could not load certificate", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", cert.CertificateContentType)
+	w.Header().Set("Content-Disposition", "inline")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, f); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// ImageError writes a rejected upload with a matching status code
+func ImageError(w http.ResponseWriter, err error) {
+	switch err {
+	case images.ErrTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case images.ErrUnsupported, images.ErrTooManyPx:
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case store.ErrTooManyPhotos:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error: could not save image", http.StatusInternalServerError)
+	}
+}
+
+// AuthError writes an authentication or authorization failure with a matching status code
+func AuthError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := err.(*auth.Error); ok {
+		switch e.Code {
+		case auth.CodeUnauthenticated:
+			status = http.StatusUnauthorized
+		case auth.CodeForbidden:
+			status = http.StatusForbidden
+		}
 	}
+	http.Error(w, err.Error(), status)
 }
 
 // UploadImage DEPRECATED uses old way to send image up to 10MB
-// func UploadImage(w http.ResponseWriter, req *http.Request, db *postgres.Db, s3bucket *s3.S3) {
+// func UploadImage(w http.ResponseWriter, req *http.Request, db store.Store, s3bucket *s3.S3) {
 // var imgData string
 // decoder := json.NewDecoder(req.Body)
 // err := decoder.Decode(&imgData)
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/store/memory"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// fixture is a store with two households, Ann with Kora and Bob with Rex
+type fixture struct {
+	db       store.Store
+	ann, bob types.User
+	kora     types.Dog
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	db := memory.New()
+	ann, kora, err := db.InsertUserDog("Ann", "ann@example.com", "hash", "", "Kora", 3, "Shiba", "", types.DogProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, _, err := db.InsertUserDog("Bob", "bob@example.com", "hash", "", "Rex", 5, "Boxer", "", types.DogProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixture{db, ann, bob, kora}
+}
+
+// newLocal returns a local store in a temporary directory
+func newLocal(t *testing.T, publicURL string) *storage.Local {
+	t.Helper()
+	s, err := storage.NewLocal(t.TempDir(), publicURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// as returns req sent by u, or anonymously when u is nil
+func as(req *http.Request, u *types.User) *http.Request {
+	if u == nil {
+		return req
+	}
+	return req.WithContext(auth.WithViewer(req.Context(), &auth.Viewer{User: *u}))
+}
+
+// uploadRequest builds the multipart form UploadProfileImage reads, field is the file's form name
+func uploadRequest(t *testing.T, field string, body []byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile(field, "kora.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(body)
+	mw.Close()
+	req := httptest.NewRequest("POST", "/send", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func pngImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadProfileImage(t *testing.T) {
+	f := newFixture(t)
+	imgs := newLocal(t, "/images/")
+	img := pngImage(t)
+
+	tests := []struct {
+		name   string
+		viewer *types.User
+		table  string
+		id     graphql.ID
+		field  string
+		body   []byte
+		want   int
+	}{
+		{"anonymous", nil, "users", f.ann.ID, "uploadfile", img, http.StatusUnauthorized},
+		{"other user", &f.bob, "users", f.ann.ID, "uploadfile", img, http.StatusForbidden},
+		{"other user's dog", &f.bob, "dogs", f.kora.ID, "uploadfile", img, http.StatusForbidden},
+		{"missing file", &f.ann, "users", f.ann.ID, "file", img, http.StatusBadRequest},
+		{"not an image", &f.ann, "users", f.ann.ID, "uploadfile", []byte("<html></html>"), http.StatusUnsupportedMediaType},
+		{"own picture", &f.ann, "users", f.ann.ID, "uploadfile", img, http.StatusOK},
+		{"own dog", &f.ann, "dogs", f.kora.ID, "uploadfile", img, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			UploadProfileImage(w, as(uploadRequest(t, tt.field, tt.body), tt.viewer), imgs, f.db, tt.table, tt.id)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			url := w.Body.String()
+			if !strings.HasPrefix(url, "/images/"+tt.table+"/"+string(tt.id)+"/") {
+				t.Errorf("url = %q", url)
+			}
+			if _, err := imgs.Stat(context.Background(), strings.TrimPrefix(url, "/images/")); err != nil {
+				t.Errorf("stored image: %v", err)
+			}
+		})
+	}
+
+	u, err := f.db.GetUsersByIDs([]graphql.ID{f.ann.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u[f.ann.ID].ProfileImageURL, "/images/users/") {
+		t.Errorf("profile image = %q", u[f.ann.ID].ProfileImageURL)
+	}
+}
+
+func TestServeCertificate(t *testing.T) {
+	f := newFixture(t)
+	docs := newLocal(t, "")
+	ctx := context.Background()
+	pdf := []byte("%PDF-1.4 certificate")
+	if err := docs.Put(ctx, "certificates/kora/rabies", bytes.NewReader(pdf), int64(len(pdf)), storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	records := []types.Vaccination{
+		{ID: "rabies", CertificateKey: "certificates/kora/rabies", CertificateContentType: "application/pdf"},
+		{ID: "dhpp"},
+		{ID: "lost", CertificateKey: "certificates/kora/lost", CertificateContentType: "application/pdf"},
+	}
+	for _, v := range records {
+		v.Dog = f.kora.ID
+		v.Vaccine = "RABIES"
+		v.AdministeredOn = graphql.Time{Time: time.Now()}
+		if _, err := f.db.InsertVaccination(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		viewer *types.User
+		id     graphql.ID
+		want   int
+	}{
+		{"anonymous", nil, "rabies", http.StatusUnauthorized},
+		{"other user", &f.bob, "rabies", http.StatusForbidden},
+		{"unknown record", &f.ann, "lyme", http.StatusNotFound},
+		{"no certificate", &f.ann, "dhpp", http.StatusNotFound},
+		{"missing object", &f.ann, "lost", http.StatusNotFound},
+		{"owner", &f.ann, "rabies", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := as(httptest.NewRequest("GET", "/certificates/"+string(tt.id), nil), tt.viewer)
+			ServeCertificate(w, req, docs, f.db, f.kora.ID, tt.id)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			body, _ := ioutil.ReadAll(w.Body)
+			if !bytes.Equal(body, pdf) {
+				t.Errorf("body = %q", body)
+			}
+			for header, want := range map[string]string{
+				"Content-Type":  "application/pdf",
+				"Cache-Control": "private, no-store",
+			} {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
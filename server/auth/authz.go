@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+)
+
+// Error codes exposed to graphql clients under the error's extensions
+const (
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+)
+
+// Error is an authentication or authorization failure, graphql-go copies
+// Extensions into the error response so clients can switch on the code
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions function required by graphql-go to add a code to the error response
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// ErrUnauthenticated is returned when a request has no valid session
+var ErrUnauthenticated = &Error{CodeUnauthenticated, "Error: You must be logged in"}
+
+// Forbidden builds the error returned when the viewer does not own a resource
+func Forbidden(tableType string, id graphql.ID) *Error {
+	return &Error{CodeForbidden, fmt.Sprintf("Error: You are not allowed to modify %s %s", tableType, id)}
+}
+
+// RequireViewer returns the viewer on ctx or ErrUnauthenticated
+func RequireViewer(ctx context.Context) (*Viewer, error) {
+	v, ok := ViewerFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	return v, nil
+}
+
+// errNotAdmin is returned when a non admin manages admin only data
+var errNotAdmin = &Error{CodeForbidden, "Error: Only admins can do this"}
+
+// RequireAdmin returns the viewer on ctx when they are an admin
+func RequireAdmin(ctx context.Context) (*Viewer, error) {
+	v, err := RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !v.User.IsAdmin {
+		log.Printf("Forbidden: user %s is not an admin", v.User.ID)
+		return nil, errNotAdmin
+	}
+	return v, nil
+}
+
+// CanEdit checks that the viewer on ctx owns the users, dogs or doggy_dates row with id
+func CanEdit(ctx context.Context, db store.Store, tableType string, id graphql.ID) error {
+	v, err := RequireViewer(ctx)
+	if err != nil {
+		return err
+	}
+	switch tableType {
+	case "users":
+		if id == v.User.ID {
+			return nil
+		}
+	case "dogs":
+		return CanEditDogs(ctx, db, []graphql.ID{id})
+	case "doggy_dates":
+		organizer, err := db.GetDateOrganizer(id)
+		if err == nil && organizer == v.User.ID {
+			return nil
+		}
+	}
+	log.Printf("Forbidden: user %s on %s %s", v.User.ID, tableType, id)
+	return Forbidden(tableType, id)
+}
+
+// CanEditDogs checks that the viewer on ctx owns every dog in dogIds
+func CanEditDogs(ctx context.Context, db store.Store, dogIds []graphql.ID) error {
+	v, err := RequireViewer(ctx)
+	if err != nil {
+		return err
+	}
+	owners, err := db.GetDogOwners(dogIds)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	for _, id := range dogIds {
+		if owner, ok := owners[id]; !ok || owner != v.User.ID {
+			log.Printf("Forbidden: user %s on dogs %s", v.User.ID, id)
+			return Forbidden("dogs", id)
+		}
+	}
+	return nil
+}
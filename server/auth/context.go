@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+type contextKey string
+
+const viewerKey contextKey = "viewer"
+
+// Viewer is the authenticated user making the current request
+type Viewer struct {
+	User      types.User
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+// WithViewer returns a copy of ctx carrying the viewer
+func WithViewer(ctx context.Context, v *Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey, v)
+}
+
+// ViewerFromContext returns the viewer stored in ctx, if any
+func ViewerFromContext(ctx context.Context) (*Viewer, bool) {
+	v, ok := ctx.Value(viewerKey).(*Viewer)
+	return v, ok && v != nil
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/raymondvooo/doggy-date-app/server/store"
+)
+
+// Middleware validates the bearer token on a request and puts the viewer into its context.
+// Requests without an Authorization header pass through anonymously.
+func Middleware(db store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			header := req.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			token := strings.TrimPrefix(header, "Bearer ")
+			if token == header || token == "" {
+				http.Error(w, "Unauthorized: malformed Authorization header", http.StatusUnauthorized)
+				return
+			}
+			tokenHash := HashToken(token)
+			user, expiresAt, err := db.GetSession(tokenHash)
+			if err != nil {
+				log.Println("Auth Middleware: invalid or expired session ", err)
+				http.Error(w, "Unauthorized: invalid or expired session", http.StatusUnauthorized)
+				return
+			}
+			v := &Viewer{User: user, TokenHash: tokenHash, ExpiresAt: expiresAt}
+			next.ServeHTTP(w, req.WithContext(WithViewer(req.Context(), v)))
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Package compat scores how likely two dogs are to enjoy a play date from their breed, age,
+// size, energy level and how far apart they live. The rules are deterministic so the same
+// dogs always get the same score and ranking.
+package compat
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// CompatibilityFactorName enum values
+const (
+	FactorBreed     = "BREED"
+	FactorAge       = "AGE"
+	FactorSize      = "SIZE"
+	FactorEnergy    = "ENERGY"
+	FactorProximity = "PROXIMITY"
+)
+
+// Weights of each factor in the total score, in the order factors are reported. They add up to 1.
+var Weights = []struct {
+	Name   string
+	Weight float64
+}{
+	{FactorBreed, 0.15},
+	{FactorAge, 0.2},
+	{FactorSize, 0.25},
+	{FactorEnergy, 0.25},
+	{FactorProximity, 0.15},
+}
+
+// Neutral is the score of a factor when either dog's value is unknown
+const Neutral = 0.5
+
+// MaxAgeGap is the difference in years at which the age factor reaches 0
+const MaxAgeGap = 10
+
+// DistanceBand enum values, how far apart two homes are is only reported this coarsely so
+// a household cannot be located by moving one's own home around it
+const (
+	Within1Km  = "WITHIN_1KM"
+	Within5Km  = "WITHIN_5KM"
+	Within10Km = "WITHIN_10KM"
+	Within25Km = "WITHIN_25KM"
+	Within50Km = "WITHIN_50KM"
+	Farther    = "FARTHER"
+)
+
+// Bands maps the distance between homes to its band and proximity score, the first band
+// whose MaxKm the distance does not exceed applies. Farther than all of them scores 0.
+var Bands = []struct {
+	Band  string
+	MaxKm float64
+	Score float64
+}{
+	{Within1Km, 1, 1},
+	{Within5Km, 5, 0.8},
+	{Within10Km, 10, 0.6},
+	{Within25Km, 25, 0.4},
+	{Within50Km, 50, 0.2},
+}
+
+// MaxDistanceKm is the distance between homes beyond which the proximity factor is 0
+const MaxDistanceKm = 50.0
+
+// Factor is one part of a score, Score is between 0 and 1
+type Factor struct {
+	Name   string
+	Score  float64
+	Weight float64
+}
+
+// Candidate is a dog that could be suggested with where its household is
+type Candidate struct {
+	Dog  types.Dog
+	Home *types.Coordinates // nil when unknown
+}
+
+// Match is a candidate's score for a dog. Score is the weighted sum of Factors, between 0 and 1.
+type Match struct {
+	Dog      types.Dog
+	Score    float64
+	Distance *string // DistanceBand, nil when either home is unknown
+	Factors  []Factor
+}
+
+// Score rates how well dog, living at home, would get along with candidate
+func Score(dog types.Dog, home *types.Coordinates, candidate Candidate) Match {
+	m := Match{Dog: candidate.Dog}
+	proximity := Neutral
+	if home != nil && candidate.Home != nil {
+		band, score := Band(geo.DistanceKm(*home, *candidate.Home))
+		m.Distance, proximity = &band, score
+	}
+	scores := map[string]float64{
+		FactorBreed:     breedScore(dog, candidate.Dog),
+		FactorAge:       ageScore(dog, candidate.Dog),
+		FactorSize:      sizeScore(dog, candidate.Dog),
+		FactorEnergy:    energyScore(dog, candidate.Dog),
+		FactorProximity: proximity,
+	}
+	for _, w := range Weights {
+		score := round(scores[w.Name])
+		m.Factors = append(m.Factors, Factor{w.Name, score, w.Weight})
+		m.Score += w.Weight * score
+	}
+	m.Score = round(m.Score)
+	return m
+}
+
+// Rank scores every candidate for dog and returns the best limit of them, highest score first
+// and by dog id among equal scores
+func Rank(dog types.Dog, home *types.Coordinates, candidates []Candidate, limit int) []Match {
+	matches := make([]Match, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Dog.ID == dog.ID || c.Dog.Owner == dog.Owner {
+			continue // a dog's own household is not a suggestion
+		}
+		matches = append(matches, Score(dog, home, c))
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Dog.ID < matches[j].Dog.ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// breedScore is 1 for the same breed and Neutral otherwise, breeds are compared ignoring case
+func breedScore(a, b types.Dog) float64 {
+	ba, bb := strings.TrimSpace(a.Breed), strings.TrimSpace(b.Breed)
+	if ba != "" && strings.EqualFold(ba, bb) {
+		return 1
+	}
+	return Neutral
+}
+
+// ageScore falls linearly with the age gap, and is 0 when a puppy (under a year old) would
+// meet a dog that is not good with puppies
+func ageScore(a, b types.Dog) float64 {
+	if (a.Age == 0 && isFalse(b.GoodWithPuppies)) || (b.Age == 0 && isFalse(a.GoodWithPuppies)) {
+		return 0
+	}
+	gap := math.Abs(float64(a.Age - b.Age))
+	return 1 - math.Min(gap, MaxAgeGap)/MaxAgeGap
+}
+
+// sizeScore falls linearly with the number of size classes between the dogs, and is 0 when
+// they are two or more classes apart and the larger one is not good with small dogs
+func sizeScore(a, b types.Dog) float64 {
+	ia, ib := index(types.DogSizes, a.Size), index(types.DogSizes, b.Size)
+	if ia < 0 || ib < 0 {
+		return Neutral
+	}
+	larger := a
+	if ib > ia {
+		larger = b
+	}
+	gap := math.Abs(float64(ia - ib))
+	if gap >= 2 && isFalse(larger.GoodWithSmallDogs) {
+		return 0
+	}
+	return 1 - gap/float64(len(types.DogSizes)-1)
+}
+
+// energyScore falls linearly with the difference in energy levels
+func energyScore(a, b types.Dog) float64 {
+	ia, ib := index(types.EnergyLevels, a.EnergyLevel), index(types.EnergyLevels, b.EnergyLevel)
+	if ia < 0 || ib < 0 {
+		return Neutral
+	}
+	gap := math.Abs(float64(ia - ib))
+	return 1 - gap/float64(len(types.EnergyLevels)-1)
+}
+
+// Band returns the DistanceBand of km and its proximity score
+func Band(km float64) (string, float64) {
+	for _, b := range Bands {
+		if km <= b.MaxKm {
+			return b.Band, b.Score
+		}
+	}
+	return Farther, 0
+}
+
+// round keeps scores to 3 decimals so float noise never reorders equal scores
+func round(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// index returns the position of v in values, or -1 when v is nil or not one of them
+func index(values []string, v *string) int {
+	if v == nil {
+		return -1
+	}
+	for i, s := range values {
+		if s == *v {
+			return i
+		}
+	}
+	return -1
+}
+
+// isFalse reports whether an optional answer is known to be no
+func isFalse(b *bool) bool {
+	return b != nil && !*b
+}
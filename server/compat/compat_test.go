@@ -0,0 +1,158 @@
+package compat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+func str(s string) *string { return &s }
+func yes() *bool           { b := true; return &b }
+func no() *bool            { b := false; return &b }
+
+// dog builds a dog with id that is the only dog of its household
+func dog(id string, age int32, breed string, profile types.DogProfile) types.Dog {
+	return types.Dog{ID: graphql.ID(id), Owner: graphql.ID("owner-" + id), Age: age, Breed: breed, DogProfile: profile}
+}
+
+// factor returns the score of the named factor in m
+func factor(t *testing.T, m Match, name string) float64 {
+	t.Helper()
+	for _, f := range m.Factors {
+		if f.Name == name {
+			return f.Score
+		}
+	}
+	t.Fatalf("factor %s missing from %+v", name, m.Factors)
+	return 0
+}
+
+func TestWeightsSumToOne(t *testing.T) {
+	var sum float64
+	for _, w := range Weights {
+		sum += w.Weight
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("weights sum to %v, want 1", sum)
+	}
+}
+
+func TestFactors(t *testing.T) {
+	sf := func(lat, lng float64) *types.Coordinates { return &types.Coordinates{Latitude: lat, Longitude: lng} }
+	tests := []struct {
+		name   string
+		factor string
+		a, b   types.Dog
+		aHome  *types.Coordinates
+		bHome  *types.Coordinates
+		want   float64
+	}{
+		{"same breed", FactorBreed, dog("a", 3, "Shiba", types.DogProfile{}), dog("b", 3, "shiba ", types.DogProfile{}), nil, nil, 1},
+		{"different breed", FactorBreed, dog("a", 3, "Shiba", types.DogProfile{}), dog("b", 3, "Corgi", types.DogProfile{}), nil, nil, Neutral},
+		{"unknown breed", FactorBreed, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{}), nil, nil, Neutral},
+
+		{"same age", FactorAge, dog("a", 4, "", types.DogProfile{}), dog("b", 4, "", types.DogProfile{}), nil, nil, 1},
+		{"age gap", FactorAge, dog("a", 2, "", types.DogProfile{}), dog("b", 5, "", types.DogProfile{}), nil, nil, 0.7},
+		{"age gap capped", FactorAge, dog("a", 1, "", types.DogProfile{}), dog("b", 15, "", types.DogProfile{}), nil, nil, 0},
+		{"puppy meets dog not good with puppies", FactorAge, dog("a", 0, "", types.DogProfile{}), dog("b", 1, "", types.DogProfile{GoodWithPuppies: no()}), nil, nil, 0},
+		{"puppy veto either way round", FactorAge, dog("a", 1, "", types.DogProfile{GoodWithPuppies: no()}), dog("b", 0, "", types.DogProfile{}), nil, nil, 0},
+		{"puppy meets dog good with puppies", FactorAge, dog("a", 0, "", types.DogProfile{}), dog("b", 1, "", types.DogProfile{GoodWithPuppies: yes()}), nil, nil, 0.9},
+		{"puppy meets dog of unknown temper", FactorAge, dog("a", 0, "", types.DogProfile{}), dog("b", 1, "", types.DogProfile{}), nil, nil, 0.9},
+
+		{"same size", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("MEDIUM")}), dog("b", 3, "", types.DogProfile{Size: str("MEDIUM")}), nil, nil, 1},
+		{"size gap", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("SMALL")}), dog("b", 3, "", types.DogProfile{Size: str("MEDIUM")}), nil, nil, 0.75},
+		{"largest size gap", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("TOY")}), dog("b", 3, "", types.DogProfile{Size: str("GIANT")}), nil, nil, 0},
+		{"larger dog not good with small dogs", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("SMALL")}), dog("b", 3, "", types.DogProfile{Size: str("LARGE"), GoodWithSmallDogs: no()}), nil, nil, 0},
+		{"smaller dog not good with small dogs", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("SMALL"), GoodWithSmallDogs: no()}), dog("b", 3, "", types.DogProfile{Size: str("LARGE")}), nil, nil, 0.5},
+		{"one size apart is never vetoed", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("MEDIUM")}), dog("b", 3, "", types.DogProfile{Size: str("LARGE"), GoodWithSmallDogs: no()}), nil, nil, 0.75},
+		{"unknown size", FactorSize, dog("a", 3, "", types.DogProfile{Size: str("SMALL")}), dog("b", 3, "", types.DogProfile{}), nil, nil, Neutral},
+
+		{"same energy", FactorEnergy, dog("a", 3, "", types.DogProfile{EnergyLevel: str("HIGH")}), dog("b", 3, "", types.DogProfile{EnergyLevel: str("HIGH")}), nil, nil, 1},
+		{"energy gap", FactorEnergy, dog("a", 3, "", types.DogProfile{EnergyLevel: str("LOW")}), dog("b", 3, "", types.DogProfile{EnergyLevel: str("MEDIUM")}), nil, nil, 0.5},
+		{"opposite energy", FactorEnergy, dog("a", 3, "", types.DogProfile{EnergyLevel: str("LOW")}), dog("b", 3, "", types.DogProfile{EnergyLevel: str("HIGH")}), nil, nil, 0},
+		{"unknown energy", FactorEnergy, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{EnergyLevel: str("HIGH")}), nil, nil, Neutral},
+
+		{"same home", FactorProximity, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{}), sf(37.77, -122.45), sf(37.77, -122.45), 1},
+		{"about 4km apart", FactorProximity, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{}), sf(37.77, -122.45), sf(37.80, -122.43), 0.8},
+		{"about 40km apart", FactorProximity, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{}), sf(37.77, -122.45), sf(37.41, -122.45), 0.2},
+		{"beyond the cap", FactorProximity, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{}), sf(37.77, -122.45), sf(38.77, -122.45), 0},
+		{"unknown home", FactorProximity, dog("a", 3, "", types.DogProfile{}), dog("b", 3, "", types.DogProfile{}), sf(37.77, -122.45), nil, Neutral},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Score(tt.a, tt.aHome, Candidate{Dog: tt.b, Home: tt.bHome})
+			if got := factor(t, m, tt.factor); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.factor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBand(t *testing.T) {
+	tests := []struct {
+		km    float64
+		band  string
+		score float64
+	}{
+		{0, Within1Km, 1},
+		{1, Within1Km, 1},
+		{1.01, Within5Km, 0.8},
+		{9.9, Within10Km, 0.6},
+		{25, Within25Km, 0.4},
+		{MaxDistanceKm, Within50Km, 0.2},
+		{MaxDistanceKm + 0.1, Farther, 0},
+	}
+	for _, tt := range tests {
+		if band, score := Band(tt.km); band != tt.band || score != tt.score {
+			t.Errorf("Band(%v) = %s, %v, want %s, %v", tt.km, band, score, tt.band, tt.score)
+		}
+	}
+}
+
+func TestScoreIsWeightedSum(t *testing.T) {
+	a := dog("a", 3, "Shiba", types.DogProfile{Size: str("MEDIUM"), EnergyLevel: str("HIGH")})
+	b := dog("b", 5, "Shiba", types.DogProfile{Size: str("LARGE"), EnergyLevel: str("MEDIUM")})
+	m := Score(a, nil, Candidate{Dog: b})
+	// breed 1, age 0.8, size 0.75, energy 0.5, proximity unknown
+	want := round(0.15*1 + 0.2*0.8 + 0.25*0.75 + 0.25*0.5 + 0.15*Neutral)
+	if m.Score != want {
+		t.Errorf("Score = %v, want %v", m.Score, want)
+	}
+	if m.Distance != nil {
+		t.Errorf("Distance = %v, want nil without homes", *m.Distance)
+	}
+	if again := Score(a, nil, Candidate{Dog: b}); again.Score != m.Score {
+		t.Errorf("Score is not deterministic: %v then %v", m.Score, again.Score)
+	}
+}
+
+func TestRank(t *testing.T) {
+	me := dog("me", 3, "Shiba", types.DogProfile{})
+	sibling := dog("sibling", 3, "Shiba", types.DogProfile{})
+	sibling.Owner = me.Owner
+	best := dog("best", 3, "Shiba", types.DogProfile{})
+	tieB := dog("tie-b", 3, "Corgi", types.DogProfile{})
+	tieA := dog("tie-a", 3, "Pug", types.DogProfile{})
+	worst := dog("worst", 13, "Pug", types.DogProfile{})
+	candidates := []Candidate{{Dog: worst}, {Dog: tieB}, {Dog: me}, {Dog: sibling}, {Dog: best}, {Dog: tieA}}
+
+	var got []graphql.ID
+	for _, m := range Rank(me, nil, candidates, 10) {
+		got = append(got, m.Dog.ID)
+	}
+	want := []graphql.ID{"best", "tie-a", "tie-b", "worst"}
+	if len(got) != len(want) {
+		t.Fatalf("Rank = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Rank = %v, want %v", got, want)
+		}
+	}
+
+	if top := Rank(me, nil, candidates, 2); len(top) != 2 || top[0].Dog.ID != "best" || top[1].Dog.ID != "tie-a" {
+		t.Errorf("Rank with limit 2 = %+v", top)
+	}
+}
@@ -0,0 +1,62 @@
+// Package geo has the great-circle math shared by the stores and the playmate scoring.
+package geo
+
+import (
+	"errors"
+	"math"
+
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// EarthRadiusKm is the mean radius of the earth used for every distance
+const EarthRadiusKm = 6371.0
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = math.Pi * EarthRadiusKm / 180
+
+// ErrInvalidCoordinates is returned for positions off the globe
+var ErrInvalidCoordinates = errors.New("Error: latitude must be between -90 and 90 and longitude between -180 and 180")
+
+// Validate checks c is a real position
+func Validate(c types.Coordinates) error {
+	if math.IsNaN(c.Latitude) || math.IsNaN(c.Longitude) ||
+		c.Latitude < -90 || c.Latitude > 90 || c.Longitude < -180 || c.Longitude > 180 {
+		return ErrInvalidCoordinates
+	}
+	return nil
+}
+
+// DistanceKm returns the great-circle distance between a and b using the haversine formula,
+// the same formula the postgres store evaluates in SQL
+func DistanceKm(a, b types.Coordinates) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat, dLng := lat2-lat1, radians(b.Longitude-a.Longitude)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Box is a latitude/longitude range that contains every point within a radius of a center.
+// Longitude is unbounded (-180 to 180) when the circle reaches a pole or the antimeridian.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// BoundingBox returns a Box around the circle of radiusKm around c, used to narrow a
+// search before computing exact distances
+func BoundingBox(c types.Coordinates, radiusKm float64) Box {
+	dLat := radiusKm / kmPerDegree
+	b := Box{c.Latitude - dLat, c.Latitude + dLat, -180, 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		return b
+	}
+	dLng := dLat / math.Cos(radians(c.Latitude))
+	if c.Longitude-dLng > -180 && c.Longitude+dLng < 180 {
+		b.MinLng, b.MaxLng = c.Longitude-dLng, c.Longitude+dLng
+	}
+	return b
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
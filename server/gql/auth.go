@@ -0,0 +1,118 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"log"
+	"time"
+)
+
+// errInvalidCredentials is returned for both unknown emails and wrong passwords
+var errInvalidCredentials = errors.New("Error: Invalid email or password")
+
+// AuthPayloadResolver structure to resolve an AuthPayload object type to graphql
+type AuthPayloadResolver struct {
+	token     string
+	expiresAt time.Time
+	user      *UserResolver
+}
+
+// Login graphql mutation
+func (r *Resolver) Login(args struct {
+	Email    string
+	Password string
+}) (*AuthPayloadResolver, error) {
+	uid, hash, err := r.Db.GetUserCredentials(args.Email)
+	if err != nil || !auth.CheckPassword(hash, args.Password) {
+		log.Printf("Error: Failed login for %s", args.Email)
+		return nil, errInvalidCredentials
+	}
+	user, dogs, err := r.Db.GetUserByEmail(args.Email)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	token, err := auth.NewSessionToken()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	expiresAt := time.Now().Add(auth.SessionTTL)
+	if err := r.Db.InsertSession(auth.HashToken(token), uid, expiresAt); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: login graphql mutation")
+	return &AuthPayloadResolver{token, expiresAt, &UserResolver{&user, &dogs, r.Db}}, nil
+}
+
+// Me graphql query
+func (r *Resolver) Me(ctx context.Context) (*UserResolver, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, dogs, err := r.Db.GetUserByEmail(v.User.Email)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: me graphql query")
+	return &UserResolver{&user, &dogs, r.Db}, nil
+}
+
+// RefreshSession graphql mutation, swaps the current session token for a new one
+func (r *Resolver) RefreshSession(ctx context.Context) (*AuthPayloadResolver, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := auth.NewSessionToken()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	expiresAt := time.Now().Add(auth.SessionTTL)
+	if err := r.Db.RotateSession(v.TokenHash, auth.HashToken(token), expiresAt); err != nil {
+		log.Println(err)
+		return nil, auth.ErrUnauthenticated
+	}
+	user, dogs, err := r.Db.GetUserByEmail(v.User.Email)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: refreshSession graphql mutation")
+	return &AuthPayloadResolver{token, expiresAt, &UserResolver{&user, &dogs, r.Db}}, nil
+}
+
+// Logout graphql mutation, revokes the current session token
+func (r *Resolver) Logout(ctx context.Context) (bool, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Db.DeleteSession(v.TokenHash); err != nil {
+		log.Println(err)
+		return false, err
+	}
+	log.Println("Resolve: logout graphql mutation")
+	return true, nil
+}
+
+// Token function required by graphql to return the session token
+func (r *AuthPayloadResolver) Token() string {
+	return r.token
+}
+
+// ExpiresAt function required by graphql to return when the session token expires
+func (r *AuthPayloadResolver) ExpiresAt() graphql.Time {
+	return graphql.Time{Time: r.expiresAt}
+}
+
+// User function required by graphql to return the logged in User object
+func (r *AuthPayloadResolver) User() *UserResolver {
+	return r.user
+}
@@ -0,0 +1,195 @@
+package gql
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"strings"
+)
+
+// defaultPageSize is used when neither first nor last is given, maxPageSize caps both
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errInvalidCursor = errors.New("Error: Invalid cursor")
+
+// encodeCursor turns a keyset position into an opaque relay cursor
+func encodeCursor(c store.Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Key + "|" + c.ID))
+}
+
+// decodeCursor reverses encodeCursor, the id never contains '|' so the key may
+func decodeCursor(s string) (*store.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	i := strings.LastIndexByte(string(b), '|')
+	if i < 0 {
+		return nil, errInvalidCursor
+	}
+	return &store.Cursor{Key: string(b[:i]), ID: string(b[i+1:])}, nil
+}
+
+// errMixedPage is returned when a forward argument comes with a backward one, one of them would be ignored
+var errMixedPage = errors.New("Error: Paginate forward with first and after, or backward with last and before")
+
+// errPageSize is returned for a first or last outside 0 to maxPageSize
+var errPageSize = fmt.Errorf("Error: Page size must be between 0 and %d", maxPageSize)
+
+// newPage converts relay connection arguments into a keyset page
+func newPage(first *int32, after *string, last *int32, before *string) (store.Page, error) {
+	if first != nil && last != nil {
+		return store.Page{}, errors.New("Error: Cannot paginate with both first and last")
+	}
+	if (first != nil || after != nil) && (last != nil || before != nil) {
+		return store.Page{}, errMixedPage
+	}
+	page := store.Page{Limit: defaultPageSize, Backward: last != nil || (first == nil && before != nil)}
+	size := first
+	cursor := after
+	if page.Backward {
+		size = last
+		cursor = before
+	}
+	if size != nil {
+		if *size < 0 || *size > maxPageSize {
+			return store.Page{}, errPageSize
+		}
+		page.Limit = int(*size)
+	}
+	if cursor != nil {
+		c, err := decodeCursor(*cursor)
+		if err != nil {
+			return store.Page{}, err
+		}
+		page.Cursor = c
+	}
+	return page, nil
+}
+
+// PageInfoResolver structure to resolve a PageInfo object type to graphql
+type PageInfoResolver struct {
+	startCursor     *string
+	endCursor       *string
+	hasNextPage     bool
+	hasPreviousPage bool
+}
+
+// newPageInfo builds the PageInfo of a page given the cursors of its edges and whether more
+// rows exist in the direction of travel
+func newPageInfo(page store.Page, cursors []string, more bool) *PageInfoResolver {
+	p := &PageInfoResolver{
+		hasNextPage:     more,
+		hasPreviousPage: page.Cursor != nil,
+	}
+	if page.Backward {
+		p.hasNextPage, p.hasPreviousPage = p.hasPreviousPage, p.hasNextPage
+	}
+	if len(cursors) > 0 {
+		p.startCursor = &cursors[0]
+		p.endCursor = &cursors[len(cursors)-1]
+	}
+	return p
+}
+
+// StartCursor function required by graphql to return the cursor of the first edge
+func (r *PageInfoResolver) StartCursor() *string {
+	return r.startCursor
+}
+
+// EndCursor function required by graphql to return the cursor of the last edge
+func (r *PageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+// HasNextPage function required by graphql
+func (r *PageInfoResolver) HasNextPage() bool {
+	return r.hasNextPage
+}
+
+// HasPreviousPage function required by graphql
+func (r *PageInfoResolver) HasPreviousPage() bool {
+	return r.hasPreviousPage
+}
+
+// DoggyDateEdgeResolver structure to resolve a DoggyDateEdge object type to graphql
+type DoggyDateEdgeResolver struct {
+	cursor string
+	node   *DoggyDateResolver
+}
+
+// Cursor function required by graphql
+func (r *DoggyDateEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+// Node function required by graphql
+func (r *DoggyDateEdgeResolver) Node() *DoggyDateResolver {
+	return r.node
+}
+
+// DoggyDateConnectionResolver structure to resolve a DoggyDateConnection object type to graphql
+type DoggyDateConnectionResolver struct {
+	edges    []*DoggyDateEdgeResolver
+	pageInfo *PageInfoResolver
+}
+
+// Edges function required by graphql
+func (r *DoggyDateConnectionResolver) Edges() *[]*DoggyDateEdgeResolver {
+	return &r.edges
+}
+
+// PageInfo function required by graphql
+func (r *DoggyDateConnectionResolver) PageInfo() *PageInfoResolver {
+	return r.pageInfo
+}
+
+// DogEdgeResolver structure to resolve a DogEdge object type to graphql
+type DogEdgeResolver struct {
+	cursor string
+	node   *DogResolver
+}
+
+// Cursor function required by graphql
+func (r *DogEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+// Node function required by graphql
+func (r *DogEdgeResolver) Node() *DogResolver {
+	return r.node
+}
+
+// DogConnectionResolver structure to resolve a DogConnection object type to graphql
+type DogConnectionResolver struct {
+	edges    []*DogEdgeResolver
+	pageInfo *PageInfoResolver
+}
+
+// Edges function required by graphql
+func (r *DogConnectionResolver) Edges() *[]*DogEdgeResolver {
+	return &r.edges
+}
+
+// PageInfo function required by graphql
+func (r *DogConnectionResolver) PageInfo() *PageInfoResolver {
+	return r.pageInfo
+}
+
+// connectionArgs are the relay pagination arguments shared by connection fields
+type connectionArgs struct {
+	First  *int32
+	After  *string
+	Last   *int32
+	Before *string
+}
+
+// dogCursor returns the cursor of a dog ordered by name then id
+func dogCursor(name string, id graphql.ID) string {
+	return encodeCursor(store.Cursor{Key: name, ID: string(id)})
+}
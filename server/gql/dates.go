@@ -0,0 +1,230 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+)
+
+// errDateCancelled is returned when changing a doggy date that was already cancelled
+var errDateCancelled = errors.New("Error: Doggy date has been cancelled")
+
+// editableDate checks the viewer organized the doggy date and it is still open for changes
+func (r *Resolver) editableDate(ctx context.Context, id graphql.ID) error {
+	if err := auth.CanEdit(ctx, r.Db, "doggy_dates", id); err != nil {
+		return err
+	}
+	date, err := r.Db.GetDoggyDateByID(id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if date.Status == types.DateStatusCancelled {
+		return errDateCancelled
+	}
+	return nil
+}
+
+// UpdateDate graphql mutation, reschedules or relocates a doggy date
+func (r *Resolver) UpdateDate(ctx context.Context, args *struct {
+	ID          graphql.ID
+	Date        *graphql.Time
+	Description *string
+	Location    *string
+	Status      *string
+}) (*DoggyDateResolver, error) {
+	if err := r.editableDate(ctx, args.ID); err != nil {
+		return nil, err
+	}
+	if args.Status != nil && *args.Status == types.DateStatusCancelled {
+		return nil, errors.New("Error: Use cancelDate to cancel a doggy date")
+	}
+	date, err := r.Db.UpdateDoggyDate(args.ID, args.Date, args.Description, args.Location, args.Status)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: updateDate graphql mutation")
+	return &DoggyDateResolver{&date, r.Db}, nil
+}
+
+// CancelDate graphql mutation, keeps the doggy date with a cancelled status
+func (r *Resolver) CancelDate(ctx context.Context, args *struct {
+	ID     graphql.ID
+	Reason *string
+}) (*DoggyDateResolver, error) {
+	if err := r.editableDate(ctx, args.ID); err != nil {
+		return nil, err
+	}
+	var reason string
+	if args.Reason != nil {
+		reason = *args.Reason
+	}
+	date, err := r.Db.CancelDoggyDate(args.ID, reason)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: cancelDate graphql mutation")
+	return &DoggyDateResolver{&date, r.Db}, nil
+}
+
+// DeleteDate graphql mutation
+func (r *Resolver) DeleteDate(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := auth.CanEdit(ctx, r.Db, "doggy_dates", args.ID); err != nil {
+		return false, err
+	}
+	if err := r.Db.DeleteDoggyDate(args.ID); err != nil {
+		log.Println(err)
+		return false, err
+	}
+	log.Println("Resolve: deleteDate graphql mutation")
+	return true, nil
+}
+
+// doggyDateFilter is the DoggyDateFilter graphql input
+type doggyDateFilter struct {
+	From      *graphql.Time
+	To        *graphql.Time
+	Location  *string
+	Organizer *graphql.ID
+	Dog       *graphql.ID
+	Breed     *string
+	Status    *[]string
+}
+
+// toStore converts the graphql input into a store.DateFilter, a nil filter matches everything
+func (f *doggyDateFilter) toStore() store.DateFilter {
+	var sf store.DateFilter
+	if f == nil {
+		return sf
+	}
+	if f.From != nil {
+		sf.From = &f.From.Time
+	}
+	if f.To != nil {
+		sf.To = &f.To.Time
+	}
+	if f.Location != nil {
+		sf.Location = *f.Location
+	}
+	if f.Organizer != nil {
+		sf.Organizer = *f.Organizer
+	}
+	if f.Dog != nil {
+		sf.Dog = *f.Dog
+	}
+	if f.Breed != nil {
+		sf.Breed = *f.Breed
+	}
+	if f.Status != nil {
+		sf.Statuses = *f.Status
+	}
+	return sf
+}
+
+// maxRadiusKm caps the radius of nearbyDates
+const maxRadiusKm = 100
+
+var (
+	errHalfCoordinates = errors.New("Error: latitude and longitude must be given together")
+	errRadius          = fmt.Errorf("Error: radiusKm must be greater than 0 and at most %d", maxRadiusKm)
+)
+
+// dateCoordinates validates an optional latitude and longitude pair
+func dateCoordinates(lat, lng *float64) (*types.Coordinates, error) {
+	if lat == nil && lng == nil {
+		return nil, nil
+	}
+	if lat == nil || lng == nil {
+		return nil, errHalfCoordinates
+	}
+	c := types.Coordinates{Latitude: *lat, Longitude: *lng}
+	if err := geo.Validate(c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Latitude function required by graphql to return where the DoggyDate is on a map
+func (r *DoggyDateResolver) Latitude() *float64 {
+	if r.date.Coordinates == nil {
+		return nil
+	}
+	return &r.date.Coordinates.Latitude
+}
+
+// Longitude function required by graphql to return where the DoggyDate is on a map
+func (r *DoggyDateResolver) Longitude() *float64 {
+	if r.date.Coordinates == nil {
+		return nil
+	}
+	return &r.date.Coordinates.Longitude
+}
+
+// PlaceName function required by graphql to return the name of where the DoggyDate is
+func (r *DoggyDateResolver) PlaceName() *string {
+	if r.date.PlaceName == "" {
+		return nil
+	}
+	return &r.date.PlaceName
+}
+
+// NearbyDoggyDateResolver resolves a doggy date found by nearbyDates
+type NearbyDoggyDateResolver struct {
+	date       *DoggyDateResolver
+	distanceKm float64
+}
+
+// Date function required by graphql to return the DoggyDate
+func (r *NearbyDoggyDateResolver) Date() *DoggyDateResolver {
+	return r.date
+}
+
+// DistanceKm function required by graphql to return how far the DoggyDate is from the search center
+func (r *NearbyDoggyDateResolver) DistanceKm() float64 {
+	return r.distanceKm
+}
+
+// NearbyDates graphql query, returns the open doggy dates within radiusKm closest first
+func (r *Resolver) NearbyDates(args struct {
+	Lat      float64
+	Lng      float64
+	RadiusKm float64
+	From     *graphql.Time
+	To       *graphql.Time
+	First    *int32
+}) ([]*NearbyDoggyDateResolver, error) {
+	center, err := dateCoordinates(&args.Lat, &args.Lng)
+	if err != nil {
+		return nil, err
+	}
+	if args.RadiusKm <= 0 || args.RadiusKm > maxRadiusKm {
+		return nil, errRadius
+	}
+	limit := defaultPageSize
+	if args.First != nil {
+		if *args.First < 0 || *args.First > maxPageSize {
+			return nil, errPageSize
+		}
+		limit = int(*args.First)
+	}
+	filter := (&doggyDateFilter{From: args.From, To: args.To}).toStore()
+	nearby, err := r.Db.NearbyDates(*center, args.RadiusKm, filter, limit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	var out []*NearbyDoggyDateResolver
+	for i := range nearby {
+		out = append(out, &NearbyDoggyDateResolver{&DoggyDateResolver{&nearby[i].Date, r.Db}, nearby[i].DistanceKm})
+	}
+	log.Println("Resolve: nearbyDates graphql query")
+	return out, nil
+}
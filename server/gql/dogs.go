@@ -0,0 +1,183 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+)
+
+// maxWeightKg is well above the heaviest dogs, it catches pounds entered as kilograms
+const maxWeightKg = 120
+
+// validateDog checks the editable dog fields, nil fields are skipped
+func validateDog(name *string, age *int32) error {
+	if name != nil && *name == "" {
+		return errors.New("Error: Dog name cannot be empty")
+	}
+	if age != nil && *age < 0 {
+		return errors.New("Error: Dog age cannot be negative")
+	}
+	return nil
+}
+
+// dogProfileInput is the DogProfileInput graphql input, nil fields are left unset
+type dogProfileInput struct {
+	Size              *string
+	WeightKg          *float64
+	Sex               *string
+	Neutered          *bool
+	EnergyLevel       *string
+	PlayStyles        *[]string
+	GoodWithSmallDogs *bool
+	GoodWithPuppies   *bool
+	GoodWithPeople    *bool
+}
+
+// profile validates the input and converts it to a types.DogProfile, a nil input is an empty profile
+func (in *dogProfileInput) profile() (types.DogProfile, error) {
+	if in == nil {
+		return types.DogProfile{}, nil
+	}
+	if in.WeightKg != nil && (*in.WeightKg <= 0 || *in.WeightKg > maxWeightKg) {
+		return types.DogProfile{}, fmt.Errorf("Error: Dog weight must be more than 0 and at most %d kg", maxWeightKg)
+	}
+	p := types.DogProfile{
+		Size:              in.Size,
+		WeightKg:          in.WeightKg,
+		Sex:               in.Sex,
+		Neutered:          in.Neutered,
+		EnergyLevel:       in.EnergyLevel,
+		GoodWithSmallDogs: in.GoodWithSmallDogs,
+		GoodWithPuppies:   in.GoodWithPuppies,
+		GoodWithPeople:    in.GoodWithPeople,
+	}
+	if in.PlayStyles != nil {
+		p.PlayStyles = dedupeStrings(*in.PlayStyles)
+	}
+	return p, nil
+}
+
+// AddDog graphql mutation, adds a dog to the logged in user
+func (r *Resolver) AddDog(ctx context.Context, args *struct {
+	Name            string
+	Age             int32
+	Breed           string
+	ProfileImageURL *string
+	Profile         *dogProfileInput
+}) (*DogResolver, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDog(&args.Name, &args.Age); err != nil {
+		return nil, err
+	}
+	profile, err := args.Profile.profile()
+	if err != nil {
+		return nil, err
+	}
+	var img string
+	if args.ProfileImageURL != nil {
+		img = *args.ProfileImageURL
+	}
+	dog, err := r.Db.InsertDog(v.User.ID, args.Name, args.Age, args.Breed, img, profile)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: addDog graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{dog.ID})
+}
+
+// UpdateDog graphql mutation
+func (r *Resolver) UpdateDog(ctx context.Context, args *struct {
+	ID      graphql.ID
+	Name    *string
+	Age     *int32
+	Breed   *string
+	Profile *dogProfileInput
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.ID); err != nil {
+		return nil, err
+	}
+	if err := validateDog(args.Name, args.Age); err != nil {
+		return nil, err
+	}
+	profile, err := args.Profile.profile()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Db.UpdateDog(args.ID, args.Name, args.Age, args.Breed, profile); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: updateDog graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.ID})
+}
+
+// RemoveDog graphql mutation, returns the dog's owner without the dog
+func (r *Resolver) RemoveDog(ctx context.Context, args struct{ ID graphql.ID }) (*UserResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.ID); err != nil {
+		return nil, err
+	}
+	owner, objs, err := r.Db.DeleteDog(args.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	r.deleteObjects(ctx, objs)
+	log.Println("Resolve: removeDog graphql mutation")
+	return r.User(struct{ ID graphql.ID }{owner})
+}
+
+// Size function required by graphql to return dog's size class
+func (r *DogResolver) Size() *string {
+	return r.d.Size
+}
+
+// WeightKg function required by graphql to return dog's weight
+func (r *DogResolver) WeightKg() *float64 {
+	return r.d.WeightKg
+}
+
+// Sex function required by graphql to return dog's sex
+func (r *DogResolver) Sex() *string {
+	return r.d.Sex
+}
+
+// Neutered function required by graphql to return whether the dog is spayed or neutered
+func (r *DogResolver) Neutered() *bool {
+	return r.d.Neutered
+}
+
+// EnergyLevel function required by graphql to return dog's energy level
+func (r *DogResolver) EnergyLevel() *string {
+	return r.d.EnergyLevel
+}
+
+// PlayStyles function required by graphql to return how the dog likes to play
+func (r *DogResolver) PlayStyles() []string {
+	if r.d.PlayStyles == nil {
+		return []string{}
+	}
+	return r.d.PlayStyles
+}
+
+// GoodWithSmallDogs function required by graphql to return whether the dog is good with small dogs
+func (r *DogResolver) GoodWithSmallDogs() *bool {
+	return r.d.GoodWithSmallDogs
+}
+
+// GoodWithPuppies function required by graphql to return whether the dog is good with puppies
+func (r *DogResolver) GoodWithPuppies() *bool {
+	return r.d.GoodWithPuppies
+}
+
+// GoodWithPeople function required by graphql to return whether the dog is good with people
+func (r *DogResolver) GoodWithPeople() *bool {
+	return r.d.GoodWithPeople
+}
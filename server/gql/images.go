@@ -0,0 +1,220 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/images"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
+	uuid "github.com/satori/go.uuid"
+)
+
+// uploadTTL is how long a presigned upload policy stays valid
+const uploadTTL = 15 * time.Minute
+
+// imageEntities maps the ImageEntity enum to table types
+var imageEntities = map[string]string{
+	"USER": "users",
+	"DOG":  "dogs",
+}
+
+// uploadContentTypes are the Content-Types accepted by requestImageUpload
+var uploadContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+var (
+	errNoDirectUpload = errors.New("Error: the configured image store does not support direct uploads")
+	errUploadKey      = errors.New("Error: key was not issued for this upload")
+)
+
+// imageSizeArgs is the optional ImageSize argument of profileImageURL
+type imageSizeArgs struct {
+	Size *string
+}
+
+// size converts the ImageSize enum, defaulting to the full size image
+func (a imageSizeArgs) size() images.Size {
+	if a.Size == nil {
+		return images.Full
+	}
+	return images.Size(strings.ToLower(*a.Size))
+}
+
+// uploadPrefix is where direct uploads for an entity land in the private document store
+// before they are confirmed, so nothing is served publicly until it has been processed.
+// Unconfirmed uploads are never cleaned up by the server, expire them with a bucket lifecycle rule.
+func uploadPrefix(tableType string, id graphql.ID) string {
+	return fmt.Sprintf("uploads/%s/%s/", tableType, id)
+}
+
+// ImageUploadResolver resolves the presigned upload returned by requestImageUpload
+type ImageUploadResolver struct {
+	key       string
+	url       string
+	fields    map[string]string
+	expiresAt time.Time
+}
+
+// UploadFieldResolver resolves a form field of a presigned upload
+type UploadFieldResolver struct {
+	name  string
+	value string
+}
+
+// Name function required by graphql to return the form field name
+func (r *UploadFieldResolver) Name() string {
+	return r.name
+}
+
+// Value function required by graphql to return the form field value
+func (r *UploadFieldResolver) Value() string {
+	return r.value
+}
+
+// Key function required by graphql to return the key to pass to confirmImageUpload
+func (r *ImageUploadResolver) Key() string {
+	return r.key
+}
+
+// URL function required by graphql to return the presigned URL to POST the image to
+func (r *ImageUploadResolver) URL() string {
+	return r.url
+}
+
+// Fields function required by graphql to return the form fields to send before the image, sorted by name
+func (r *ImageUploadResolver) Fields() []*UploadFieldResolver {
+	res := []*UploadFieldResolver{}
+	for name, value := range r.fields {
+		res = append(res, &UploadFieldResolver{name, value})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res
+}
+
+// ExpiresAt function required by graphql to return when the URL stops working
+func (r *ImageUploadResolver) ExpiresAt() graphql.Time {
+	return graphql.Time{Time: r.expiresAt}
+}
+
+// RequestImageUpload graphql mutation, returns a URL the client uploads the image to directly
+func (r *Resolver) RequestImageUpload(ctx context.Context, args struct {
+	Entity      string
+	ID          graphql.ID
+	ContentType string
+	Size        int32
+}) (*ImageUploadResolver, error) {
+	tableType := imageEntities[args.Entity]
+	if err := auth.CanEdit(ctx, r.Db, tableType, args.ID); err != nil {
+		return nil, err
+	}
+	presigner, ok := r.Documents.(storage.Presigner)
+	if !ok {
+		return nil, errNoDirectUpload
+	}
+	if !uploadContentTypes[args.ContentType] {
+		return nil, images.ErrUnsupported
+	}
+	if args.Size <= 0 || args.Size > images.MaxBytes {
+		return nil, images.ErrTooLarge
+	}
+	uid, _ := uuid.NewV4()
+	key := uploadPrefix(tableType, args.ID) + uid.String()
+	// The policy only accepts the announced type and at most MaxBytes
+	url, fields, err := presigner.PresignPost(ctx, key, args.ContentType, images.MaxBytes, uploadTTL)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: requestImageUpload graphql mutation")
+	return &ImageUploadResolver{key, url, fields, time.Now().Add(uploadTTL)}, nil
+}
+
+// ConfirmImageUpload graphql mutation, processes a direct upload into the entity's
+// profile picture and returns its URL
+func (r *Resolver) ConfirmImageUpload(ctx context.Context, args struct {
+	Entity string
+	ID     graphql.ID
+	Key    string
+}) (string, error) {
+	tableType := imageEntities[args.Entity]
+	if err := auth.CanEdit(ctx, r.Db, tableType, args.ID); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(args.Key, uploadPrefix(tableType, args.ID)) || strings.Contains(args.Key, "..") {
+		return "", errUploadKey
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// The policy limits the size already, checking again costs nothing
+	size, err := r.Documents.Stat(ctx, args.Key)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	defer func() {
+		if err := r.Documents.Delete(ctx, args.Key); err != nil {
+			log.Println("Delete upload Error: ", err)
+		}
+	}()
+	if size > images.MaxBytes {
+		return "", images.ErrTooLarge
+	}
+	obj, err := r.Documents.Get(ctx, args.Key)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	defer obj.Close()
+	url, err := images.Save(ctx, r.Images, r.Db, tableType, args.ID, obj)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	log.Println("Resolve: confirmImageUpload graphql mutation")
+	return url, nil
+}
+
+// SetDogPhoto graphql mutation, makes an uploaded file the dog's picture
+func (r *Resolver) SetDogPhoto(ctx context.Context, args struct {
+	DogID graphql.ID
+	File  Upload
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := images.Save(ctx, r.Images, r.Db, "dogs", args.DogID, args.File.File); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: setDogPhoto graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.DogID})
+}
+
+// SetUserPhoto graphql mutation, makes an uploaded file the logged in user's picture
+func (r *Resolver) SetUserPhoto(ctx context.Context, args struct{ File Upload }) (*UserResolver, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := images.Save(ctx, r.Images, r.Db, "users", v.User.ID, args.File.File); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: setUserPhoto graphql mutation")
+	return r.User(struct{ ID graphql.ID }{v.User.ID})
+}
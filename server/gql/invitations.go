@@ -0,0 +1,174 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+)
+
+// InvitationResolver structure to resolve an Invitation object type to graphql
+type InvitationResolver struct {
+	inv *types.Invitation
+	Db  store.Store
+}
+
+// rsvpStatus maps an RSVPResponse enum value to the stored InvitationStatus
+var rsvpStatus = map[string]string{
+	"ACCEPT":  types.InvitationAccepted,
+	"DECLINE": types.InvitationDeclined,
+	"MAYBE":   types.InvitationMaybe,
+}
+
+// newInvitationResolvers wraps each invitation in an InvitationResolver
+func newInvitationResolvers(invs []types.Invitation, db store.Store) *[]*InvitationResolver {
+	var ir []*InvitationResolver
+	for i := 0; i < len(invs); i++ {
+		ir = append(ir, &InvitationResolver{&invs[i], db})
+	}
+	return &ir
+}
+
+// InviteToDate graphql mutation, the organizer invites another owner's dog
+func (r *Resolver) InviteToDate(ctx context.Context, args *struct {
+	DateID graphql.ID
+	DogID  graphql.ID
+}) (*InvitationResolver, error) {
+	if err := r.editableDate(ctx, args.DateID); err != nil {
+		return nil, err
+	}
+	v, _ := auth.RequireViewer(ctx)
+	owners, err := r.Db.GetDogOwners([]graphql.ID{args.DogID})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	owner, ok := owners[args.DogID]
+	if !ok {
+		return nil, errors.New("Error: Dog does not exist")
+	}
+	if owner == v.User.ID {
+		return nil, errors.New("Error: Cannot invite your own dog")
+	}
+	inv, err := r.Db.InsertInvitation(args.DateID, args.DogID, v.User.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: inviteToDate graphql mutation")
+	return &InvitationResolver{&inv, r.Db}, nil
+}
+
+// RespondToInvite graphql mutation, the invited dog's owner answers an invitation
+func (r *Resolver) RespondToInvite(ctx context.Context, args *struct {
+	ID       graphql.ID
+	Response string
+}) (*InvitationResolver, error) {
+	inv, err := r.Db.GetInvitationByID(args.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, errors.New("Error: Invitation does not exist")
+	}
+	if err := auth.CanEdit(ctx, r.Db, "dogs", inv.Dog); err != nil {
+		return nil, err
+	}
+	date, err := r.Db.GetDoggyDateByID(inv.Date)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if date.Status == types.DateStatusCancelled {
+		return nil, errDateCancelled
+	}
+	// An accepted dog joins the date, so it must meet the date's vaccination requirements
+	if args.Response == "ACCEPT" {
+		if err := r.checkVaccinations(ctx, []graphql.ID{inv.Dog}, date.RequiresVaccinations, date.Date); err != nil {
+			return nil, err
+		}
+	}
+	inv, err = r.Db.RespondToInvitation(args.ID, rsvpStatus[args.Response])
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: respondToInvite graphql mutation")
+	return &InvitationResolver{&inv, r.Db}, nil
+}
+
+// Invitations function required by graphql to return DoggyDate's invitations
+func (r *DoggyDateResolver) Invitations() (*[]*InvitationResolver, error) {
+	invs, err := r.Db.GetInvitationsByDate(r.date.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return newInvitationResolvers(invs, r.Db), nil
+}
+
+// PendingInvitations function required by graphql to return invitations awaiting the user,
+// only visible to the user themselves
+func (r *UserResolver) PendingInvitations(ctx context.Context) (*[]*InvitationResolver, error) {
+	if v, ok := auth.ViewerFromContext(ctx); !ok || v.User.ID != r.u.ID {
+		return nil, nil
+	}
+	invs, err := r.Db.GetPendingInvitationsByOwner(r.u.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return newInvitationResolvers(invs, r.Db), nil
+}
+
+// ID function required by graphql to return Invitation's ID
+func (r *InvitationResolver) ID() graphql.ID {
+	return r.inv.ID
+}
+
+// Status function required by graphql to return Invitation's status
+func (r *InvitationResolver) Status() string {
+	return r.inv.Status
+}
+
+// CreatedAt function required by graphql to return when the Invitation was sent
+func (r *InvitationResolver) CreatedAt() graphql.Time {
+	return r.inv.CreatedAt
+}
+
+// RespondedAt function required by graphql to return when the Invitation was last answered
+func (r *InvitationResolver) RespondedAt() *graphql.Time {
+	return r.inv.RespondedAt
+}
+
+// Date function required by graphql to return the Invitation's DoggyDate
+func (r *InvitationResolver) Date(ctx context.Context) (*DoggyDateResolver, error) {
+	date, err := loader.LoadDate(ctx, r.inv.Date)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &DoggyDateResolver{&date, r.Db}, nil
+}
+
+// Dog function required by graphql to return the invited Dog
+func (r *InvitationResolver) Dog(ctx context.Context) (*DogResolver, error) {
+	dog, err := loader.LoadDog(ctx, r.inv.Dog)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &DogResolver{&dog, r.Db}, nil
+}
+
+// InvitedBy function required by graphql to return the User who sent the Invitation
+func (r *InvitationResolver) InvitedBy(ctx context.Context) (*UserResolver, error) {
+	u, err := loader.LoadUser(ctx, r.inv.InvitedBy)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &UserResolver{&u, nil, r.Db}, nil
+}
@@ -0,0 +1,181 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+)
+
+var (
+	errLikeOwnHousehold = errors.New("Error: Dogs of the same household cannot like or pass each other")
+	errNotMatched       = errors.New("Error: matchedDog is not matched with any of the date's dogs")
+)
+
+// LikeResolver resolves a dog's like or pass of another dog
+type LikeResolver struct {
+	l     *types.Like
+	match *types.Match // set when the like is mutual
+	Db    store.Store
+}
+
+// MatchResolver resolves a mutual like seen from one of the dogs
+type MatchResolver struct {
+	m  *types.Match
+	Db store.Store
+}
+
+type likeArgs struct {
+	FromDog graphql.ID
+	ToDog   graphql.ID
+}
+
+// LikeDog graphql mutation, the owner of fromDog likes toDog for it
+func (r *Resolver) LikeDog(ctx context.Context, args likeArgs) (*LikeResolver, error) {
+	like, err := r.decide(ctx, args, true)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Resolve: likeDog graphql mutation")
+	return like, nil
+}
+
+// PassDog graphql mutation, the owner of fromDog passes on toDog for it
+func (r *Resolver) PassDog(ctx context.Context, args likeArgs) (*LikeResolver, error) {
+	like, err := r.decide(ctx, args, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Resolve: passDog graphql mutation")
+	return like, nil
+}
+
+// decide records fromDog's like or pass of toDog, a like also looks for the match it makes
+func (r *Resolver) decide(ctx context.Context, args likeArgs, liked bool) (*LikeResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.FromDog); err != nil {
+		return nil, err
+	}
+	owners, err := r.Db.GetDogOwners([]graphql.ID{args.FromDog, args.ToDog})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	owner, ok := owners[args.ToDog]
+	if !ok {
+		return nil, errors.New("Error: Dog does not exist")
+	}
+	if owner == owners[args.FromDog] {
+		return nil, errLikeOwnHousehold
+	}
+	like, err := r.Db.InsertLike(types.Like{From: args.FromDog, To: args.ToDog, Liked: liked})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	res := &LikeResolver{&like, nil, r.Db}
+	if liked {
+		if res.match, err = r.findMatch(args.FromDog, args.ToDog); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// findMatch returns the match of dog with other, nil unless they like each other
+func (r *Resolver) findMatch(dog graphql.ID, other graphql.ID) (*types.Match, error) {
+	matches, err := r.Db.GetMatches(dog)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	for i := range matches {
+		if matches[i].Dog == other {
+			return &matches[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// checkMatched returns errNotMatched unless matched is a match of one of dogs
+func (r *Resolver) checkMatched(dogs []graphql.ID, matched graphql.ID) error {
+	for _, dog := range dogs {
+		m, err := r.findMatch(dog, matched)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			return nil
+		}
+	}
+	return errNotMatched
+}
+
+// Matches graphql query, returns the dogs that liked the dog back, only visible to its owner
+func (r *Resolver) Matches(ctx context.Context, args struct{ DogID graphql.ID }) ([]*MatchResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	matches, err := r.Db.GetMatches(args.DogID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	res := []*MatchResolver{}
+	for i := range matches {
+		res = append(res, &MatchResolver{&matches[i], r.Db})
+	}
+	log.Println("Resolve: matches graphql query")
+	return res, nil
+}
+
+// FromDog function required by graphql to return the dog that liked or passed
+func (r *LikeResolver) FromDog(ctx context.Context) (*DogResolver, error) {
+	return loadDogResolver(ctx, r.l.From, r.Db)
+}
+
+// ToDog function required by graphql to return the dog that was liked or passed
+func (r *LikeResolver) ToDog(ctx context.Context) (*DogResolver, error) {
+	return loadDogResolver(ctx, r.l.To, r.Db)
+}
+
+// Liked function required by graphql to return whether it is a like rather than a pass
+func (r *LikeResolver) Liked() bool {
+	return r.l.Liked
+}
+
+// CreatedAt function required by graphql to return when the dog decided
+func (r *LikeResolver) CreatedAt() graphql.Time {
+	return r.l.CreatedAt
+}
+
+// Match function required by graphql to return the match the like made, if the other dog liked back
+func (r *LikeResolver) Match() *MatchResolver {
+	if r.match == nil {
+		return nil
+	}
+	return &MatchResolver{r.match, r.Db}
+}
+
+// Dog function required by graphql to return the other dog of the match
+func (r *MatchResolver) Dog(ctx context.Context) (*DogResolver, error) {
+	return loadDogResolver(ctx, r.m.Dog, r.Db)
+}
+
+// MatchedAt function required by graphql to return when the second of the two likes was made
+func (r *MatchResolver) MatchedAt() graphql.Time {
+	return r.m.MatchedAt
+}
+
+// loadDogResolver resolves the dog with id through the request's dog loader
+func loadDogResolver(ctx context.Context, id graphql.ID, db store.Store) (*DogResolver, error) {
+	dog, err := loader.LoadDog(ctx, id)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &DogResolver{&dog, db}, nil
+}
@@ -0,0 +1,148 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/images"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+	"time"
+)
+
+var errPhotoNotFound = errors.New("Error: Photo not found")
+
+// DogPhotoResolver resolves a photo in a dog's gallery
+type DogPhotoResolver struct {
+	p *types.DogPhoto
+}
+
+// ID function required by graphql to return photo's ID
+func (r *DogPhotoResolver) ID() graphql.ID {
+	return r.p.ID
+}
+
+// URL function required by graphql to return the photo at the requested size
+func (r *DogPhotoResolver) URL(args imageSizeArgs) string {
+	return images.SizedURL(r.p.URL, args.size())
+}
+
+// Position function required by graphql to return where the photo is in the gallery, starting at 0
+func (r *DogPhotoResolver) Position() int32 {
+	return r.p.Position
+}
+
+// Primary function required by graphql to return whether the photo is the dog's profile image
+func (r *DogPhotoResolver) Primary() bool {
+	return r.p.Primary
+}
+
+// CreatedAt function required by graphql to return when the photo was added
+func (r *DogPhotoResolver) CreatedAt() graphql.Time {
+	return r.p.CreatedAt
+}
+
+// Photos function required by graphql to return dog's gallery in order
+func (r *DogResolver) Photos(ctx context.Context) ([]*DogPhotoResolver, error) {
+	photos, err := loader.LoadDogPhotos(ctx, r.d.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	res := []*DogPhotoResolver{}
+	for i := range photos {
+		res = append(res, &DogPhotoResolver{&photos[i]})
+	}
+	return res, nil
+}
+
+// AddDogPhoto graphql mutation, appends an uploaded file to the dog's gallery
+func (r *Resolver) AddDogPhoto(ctx context.Context, args struct {
+	DogID   graphql.ID
+	File    Upload
+	Primary *bool
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	primary := args.Primary != nil && *args.Primary
+	if _, err := images.SaveDogPhoto(ctx, r.Images, r.Db, args.DogID, args.File.File, primary); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: addDogPhoto graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.DogID})
+}
+
+// RemoveDogPhoto graphql mutation, deletes a photo and its stored images
+func (r *Resolver) RemoveDogPhoto(ctx context.Context, args struct {
+	DogID   graphql.ID
+	PhotoID graphql.ID
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	p, err := r.Db.DeleteDogPhoto(args.DogID, args.PhotoID)
+	if err != nil {
+		log.Println(err)
+		return nil, errPhotoNotFound
+	}
+	images.Delete(ctx, r.Images, []types.Image{{Key: p.Key, Ext: p.Ext}})
+	log.Println("Resolve: removeDogPhoto graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.DogID})
+}
+
+// ReorderDogPhotos graphql mutation, photoIds lists every photo of the dog in its new order
+func (r *Resolver) ReorderDogPhotos(ctx context.Context, args struct {
+	DogID    graphql.ID
+	PhotoIDs []graphql.ID
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	photos, err := r.Db.GetDogPhotos([]graphql.ID{args.DogID})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	current := map[graphql.ID]bool{}
+	for _, p := range photos[args.DogID] {
+		current[p.ID] = true
+	}
+	if len(args.PhotoIDs) != len(current) {
+		return nil, store.ErrPhotoOrder
+	}
+	for _, id := range args.PhotoIDs {
+		if !current[id] {
+			return nil, store.ErrPhotoOrder
+		}
+		delete(current, id) // catches repeated ids
+	}
+	if err := r.Db.ReorderDogPhotos(args.DogID, args.PhotoIDs); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: reorderDogPhotos graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.DogID})
+}
+
+// SetPrimaryDogPhoto graphql mutation, makes a photo the dog's profile image
+func (r *Resolver) SetPrimaryDogPhoto(ctx context.Context, args struct {
+	DogID   graphql.ID
+	PhotoID graphql.ID
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	if err := r.Db.SetPrimaryDogPhoto(args.DogID, args.PhotoID); err != nil {
+		log.Println(err)
+		return nil, errPhotoNotFound
+	}
+	log.Println("Resolve: setPrimaryDogPhoto graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.DogID})
+}
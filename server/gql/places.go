@@ -0,0 +1,214 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+)
+
+var (
+	errPlaceName     = errors.New("Error: Place name cannot be empty")
+	errPlaceNotFound = errors.New("Error: Place not found")
+	errNoLocation    = errors.New("Error: A doggy date needs a location or a placeId")
+)
+
+// PlaceResolver resolves a place from the directory, distanceKm is set when searched near a point
+type PlaceResolver struct {
+	p          *types.Place
+	distanceKm *float64
+}
+
+// ID function required by graphql to return place's ID
+func (r *PlaceResolver) ID() graphql.ID {
+	return r.p.ID
+}
+
+// Name function required by graphql to return place's name
+func (r *PlaceResolver) Name() string {
+	return r.p.Name
+}
+
+// Address function required by graphql to return place's address
+func (r *PlaceResolver) Address() string {
+	return r.p.Address
+}
+
+// Latitude function required by graphql to return place's latitude
+func (r *PlaceResolver) Latitude() float64 {
+	return r.p.Coordinates.Latitude
+}
+
+// Longitude function required by graphql to return place's longitude
+func (r *PlaceResolver) Longitude() float64 {
+	return r.p.Coordinates.Longitude
+}
+
+// Amenities function required by graphql to return what the place offers dogs
+func (r *PlaceResolver) Amenities() []string {
+	return r.p.Amenities
+}
+
+// DistanceKm function required by graphql to return how far the place is from the search center
+func (r *PlaceResolver) DistanceKm() *float64 {
+	return r.distanceKm
+}
+
+// Place function required by graphql to return where the DoggyDate takes place
+func (r *DoggyDateResolver) Place(ctx context.Context) (*PlaceResolver, error) {
+	if r.date.Place == "" {
+		return nil, nil
+	}
+	p, err := loader.LoadPlace(ctx, r.date.Place)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &PlaceResolver{&p, nil}, nil
+}
+
+// nearInput is the NearInput graphql input
+type nearInput struct {
+	Lat      float64
+	Lng      float64
+	RadiusKm float64
+}
+
+// Places graphql query, searches the directory by name or address and distance
+func (r *Resolver) Places(args struct {
+	Near   *nearInput
+	Search *string
+	First  *int32
+}) ([]*PlaceResolver, error) {
+	var center *types.Coordinates
+	var radiusKm float64
+	if args.Near != nil {
+		c, err := dateCoordinates(&args.Near.Lat, &args.Near.Lng)
+		if err != nil {
+			return nil, err
+		}
+		if args.Near.RadiusKm <= 0 || args.Near.RadiusKm > maxRadiusKm {
+			return nil, errRadius
+		}
+		center, radiusKm = c, args.Near.RadiusKm
+	}
+	var search string
+	if args.Search != nil {
+		search = *args.Search
+	}
+	limit := defaultPageSize
+	if args.First != nil {
+		if *args.First < 0 || *args.First > maxPageSize {
+			return nil, errPageSize
+		}
+		limit = int(*args.First)
+	}
+	found, err := r.Db.SearchPlaces(center, radiusKm, search, limit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	var places []*PlaceResolver
+	for i := range found {
+		places = append(places, &PlaceResolver{&found[i].Place, found[i].DistanceKm})
+	}
+	log.Println("Resolve: places graphql query")
+	return places, nil
+}
+
+// CreatePlace graphql mutation, admins only
+func (r *Resolver) CreatePlace(ctx context.Context, args struct {
+	Name      string
+	Address   *string
+	Latitude  float64
+	Longitude float64
+	Amenities *[]string
+}) (*PlaceResolver, error) {
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if args.Name == "" {
+		return nil, errPlaceName
+	}
+	coords, err := dateCoordinates(&args.Latitude, &args.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	place := types.Place{Name: args.Name, Coordinates: *coords, Amenities: []string{}}
+	if args.Address != nil {
+		place.Address = *args.Address
+	}
+	if args.Amenities != nil {
+		place.Amenities = dedupeStrings(*args.Amenities)
+	}
+	p, err := r.Db.InsertPlace(place)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: createPlace graphql mutation")
+	return &PlaceResolver{&p, nil}, nil
+}
+
+// UpdatePlace graphql mutation, admins only
+func (r *Resolver) UpdatePlace(ctx context.Context, args struct {
+	ID        graphql.ID
+	Name      *string
+	Address   *string
+	Latitude  *float64
+	Longitude *float64
+	Amenities *[]string
+}) (*PlaceResolver, error) {
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if args.Name != nil && *args.Name == "" {
+		return nil, errPlaceName
+	}
+	coords, err := dateCoordinates(args.Latitude, args.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	amenities := args.Amenities
+	if amenities != nil {
+		deduped := dedupeStrings(*amenities)
+		amenities = &deduped
+	}
+	p, err := r.Db.UpdatePlace(args.ID, args.Name, args.Address, coords, amenities)
+	if err != nil {
+		log.Println(err)
+		return nil, errPlaceNotFound
+	}
+	log.Println("Resolve: updatePlace graphql mutation")
+	return &PlaceResolver{&p, nil}, nil
+}
+
+// DeletePlace graphql mutation, admins only. Dates planned there keep their location.
+func (r *Resolver) DeletePlace(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return false, err
+	}
+	if err := r.Db.DeletePlace(args.ID); err != nil {
+		log.Println(err)
+		return false, errPlaceNotFound
+	}
+	log.Println("Resolve: deletePlace graphql mutation")
+	return true, nil
+}
+
+// datePlace looks up the place a new date is planned at
+func (r *Resolver) datePlace(id graphql.ID) (types.Place, error) {
+	found, err := r.Db.GetPlacesByIDs([]graphql.ID{id})
+	if err != nil {
+		log.Println(err)
+		return types.Place{}, err
+	}
+	p, ok := found[id]
+	if !ok {
+		return types.Place{}, errPlaceNotFound
+	}
+	return p, nil
+}
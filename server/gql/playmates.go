@@ -0,0 +1,124 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/compat"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"log"
+)
+
+const (
+	defaultPlaymates = 10
+	maxPlaymates     = 50
+	// maxPlaymateCandidates caps how many dogs are scored for one suggestion
+	maxPlaymateCandidates = 500
+)
+
+var (
+	errPlaymateLimit = fmt.Errorf("Error: limit must be between 0 and %d", maxPlaymates)
+	errNoHome        = errors.New("Error: Set your home with updateUser(latitude, longitude) to get playmate suggestions")
+)
+
+// PlaymateSuggestionResolver resolves a dog suggested as a playmate with its score
+type PlaymateSuggestionResolver struct {
+	m  *compat.Match
+	Db store.Store
+}
+
+// CompatibilityFactorResolver resolves one part of a playmate's score
+type CompatibilityFactorResolver struct {
+	f compat.Factor
+}
+
+// SuggestedPlaymates graphql query, ranks dogs of other households living within
+// compat.MaxDistanceKm that the dog has not liked or passed by how well they would get along
+// with it. The owner must have set their home, the closest maxPlaymateCandidates dogs are scored.
+func (r *Resolver) SuggestedPlaymates(ctx context.Context, args struct {
+	DogID graphql.ID
+	Limit *int32
+}) ([]*PlaymateSuggestionResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	limit := defaultPlaymates
+	if args.Limit != nil {
+		if *args.Limit < 0 || *args.Limit > maxPlaymates {
+			return nil, errPlaymateLimit
+		}
+		limit = int(*args.Limit)
+	}
+	dog, err := loader.LoadDog(ctx, args.DogID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	owner, err := loader.LoadUser(ctx, dog.Owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if owner.Home == nil {
+		return nil, errNoHome
+	}
+	found, err := r.Db.GetPlaymateCandidates(dog.ID, *owner.Home, compat.MaxDistanceKm, maxPlaymateCandidates)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	candidates := make([]compat.Candidate, len(found))
+	for i, p := range found {
+		home := p.Home
+		candidates[i] = compat.Candidate{Dog: p.Dog, Home: &home}
+	}
+	matches := compat.Rank(dog, owner.Home, candidates, limit)
+	res := []*PlaymateSuggestionResolver{}
+	for i := range matches {
+		res = append(res, &PlaymateSuggestionResolver{&matches[i], r.Db})
+	}
+	log.Println("Resolve: suggestedPlaymates graphql query")
+	return res, nil
+}
+
+// Dog function required by graphql to return the suggested dog
+func (r *PlaymateSuggestionResolver) Dog() *DogResolver {
+	return &DogResolver{&r.m.Dog, r.Db}
+}
+
+// Score function required by graphql to return the weighted sum of the factors, from 0 to 1
+func (r *PlaymateSuggestionResolver) Score() float64 {
+	return r.m.Score
+}
+
+// Distance function required by graphql to return roughly how far apart the households live
+func (r *PlaymateSuggestionResolver) Distance() string {
+	return *r.m.Distance // candidates always have a home
+}
+
+// Factors function required by graphql to return the breakdown of the score
+func (r *PlaymateSuggestionResolver) Factors() []*CompatibilityFactorResolver {
+	res := []*CompatibilityFactorResolver{}
+	for _, f := range r.m.Factors {
+		res = append(res, &CompatibilityFactorResolver{f})
+	}
+	return res
+}
+
+// Factor function required by graphql to return which factor was scored
+func (r *CompatibilityFactorResolver) Factor() string {
+	return r.f.Name
+}
+
+// Score function required by graphql to return the factor's score from 0 to 1
+func (r *CompatibilityFactorResolver) Score() float64 {
+	return r.f.Score
+}
+
+// Weight function required by graphql to return the factor's share of the total score
+func (r *CompatibilityFactorResolver) Weight() float64 {
+	return r.f.Weight
+}
@@ -1,40 +1,54 @@
 package gql
 
 import (
+	"context"
 	"fmt"
 	"github.com/graph-gophers/graphql-go"
-	"github.com/raymondvooo/doggy-date-app/server/postgres"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/images"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
+	"github.com/raymondvooo/doggy-date-app/server/store"
 	"github.com/raymondvooo/doggy-date-app/server/types"
 	uuid "github.com/satori/go.uuid"
 	"log"
+	"time"
 )
 
-// Resolver has a reference database
+// Resolver has a reference database and the store profile images are saved to
 type Resolver struct {
-	Db *postgres.Db
+	Db        store.Store
+	Images    storage.ImageStore
+	Documents storage.ImageStore // private, for vaccination certificates
+}
+
+// deleteObjects removes the stored files of deleted rows, failures are only logged
+func (r *Resolver) deleteObjects(ctx context.Context, objs store.Objects) {
+	images.Delete(ctx, r.Images, objs.Images)
+	for _, key := range objs.Certificates {
+		if err := r.Documents.Delete(ctx, key); err != nil {
+			log.Println("Delete certificate Error: ", err)
+		}
+	}
 }
 
 // UserResolver structure to resolve a User object type to graphql
 type UserResolver struct {
 	u  *types.User
 	d  *[]types.Dog
-	Db *postgres.Db
+	Db store.Store
 }
 
 // DogResolver structure to resolve a Dog object type to graphql
 type DogResolver struct {
-	d    *types.Dog
-	dogs *[]types.Dog
-	Db   *postgres.Db
-	o    *types.User
+	d  *types.Dog
+	Db store.Store
 }
 
 // DoggyDateResolver structure to resolve a DoggyDate object type to graphql
 type DoggyDateResolver struct {
-	date   *types.Date
-	Db     *postgres.Db
-	u      *types.User
-	dogMap *map[graphql.ID]types.Dog
+	date *types.Date
+	Db   store.Store
 }
 
 // User graphql query
@@ -46,6 +60,9 @@ func (r *Resolver) User(args struct{ ID graphql.ID }) (*UserResolver, error) {
 		return &UserResolver{nil, nil, r.Db}, err
 	}
 	user, dogs, err := r.Db.GetUserByID(uid)
+	if err == nil && user.ID == "" {
+		err = fmt.Errorf("Error: User %s does not exist", args.ID)
+	}
 	if err != nil {
 		log.Println(err)
 		return &UserResolver{nil, nil, r.Db}, err
@@ -61,26 +78,18 @@ func (r *Resolver) Dog(args struct{ ID graphql.ID }) (*DogResolver, error) {
 	did, err := uuid.FromString(string(args.ID))
 	if err != nil {
 		log.Println(err)
-		return &DogResolver{&types.Dog{}, &[]types.Dog{}, r.Db, &types.User{}}, err
+		return &DogResolver{&types.Dog{}, r.Db}, err
 	}
-	dogs, user, err := r.Db.GetDogByID(did)
-	if err != nil {
-		log.Println(err)
-		return &DogResolver{&types.Dog{}, &[]types.Dog{}, r.Db, &user}, err
+	dogs, _, err := r.Db.GetDogByID(did)
+	if err == nil && len(dogs) == 0 {
+		err = fmt.Errorf("Error: Dog %s does not exist", args.ID)
 	}
-	data := &DogResolver{&dogs[0], &dogs, r.Db, &user}
-	log.Println("Resolve: dog graphql query")
-	return data, nil
-}
-
-// LoginUser graphql query
-func (r *Resolver) LoginUser(args struct{ Email string }) (*UserResolver, error) {
-	user, dogs, err := r.Db.GetUserByEmail(args.Email)
 	if err != nil {
 		log.Println(err)
-		return &UserResolver{nil, nil, r.Db}, err
+		return &DogResolver{&types.Dog{}, r.Db}, err
 	}
-	data := &UserResolver{&user, &dogs, r.Db}
+	data := &DogResolver{&dogs[0], r.Db}
+	log.Println("Resolve: dog graphql query")
 	return data, nil
 }
 
@@ -88,17 +97,27 @@ func (r *Resolver) LoginUser(args struct{ Email string }) (*UserResolver, error)
 func (r *Resolver) CreateUser(args *struct {
 	Name                string
 	Email               string
+	Password            string
 	UserProfileImageURL string
 	DogName             string
 	DogAge              int32
 	DogBreed            string
 	DogProfileImageURL  string
+	DogProfile          *dogProfileInput
 }) (*UserResolver, error) {
-	var err error
+	profile, err := args.DogProfile.profile()
+	if err != nil {
+		return &UserResolver{&types.User{}, &[]types.Dog{}, r.Db}, err
+	}
 	emailExists, err := r.Db.CheckEmailExists(args.Email)
 	if !emailExists && err != nil {
 		log.Println("Pass: unused email")
-		user, dog, err := r.Db.InsertUserDog(args.Name, args.Email, args.UserProfileImageURL, args.DogName, args.DogAge, args.DogBreed, args.DogProfileImageURL)
+		hash, err := auth.HashPassword(args.Password)
+		if err != nil {
+			log.Println(err)
+			return &UserResolver{&types.User{}, &[]types.Dog{}, r.Db}, err
+		}
+		user, dog, err := r.Db.InsertUserDog(args.Name, args.Email, hash, args.UserProfileImageURL, args.DogName, args.DogAge, args.DogBreed, args.DogProfileImageURL, profile)
 		if err != nil {
 			log.Println(err)
 			return &UserResolver{&types.User{}, &[]types.Dog{}, r.Db}, err
@@ -125,14 +144,45 @@ func (r *UserResolver) Email() *string {
 	return &r.u.Email
 }
 
-// Dogs function required by graphql to return user's Dog array object
-func (r *UserResolver) Dogs() *[]*DogResolver {
+// Dogs function required by graphql to return user's Dog array object,
+// dogs not fetched along with the user are batch loaded
+func (r *UserResolver) Dogs(ctx context.Context) (*[]*DogResolver, error) {
+	if r.d == nil {
+		d, err := loader.LoadDogs(ctx, r.u.Dogs)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		r.d = &d
+	}
 	var dogs []*DogResolver
 	for i := 0; i < len(*r.d); i++ {
 		d := &(*r.d)[i]
-		dogs = append(dogs, &DogResolver{d, r.d, r.Db, r.u})
+		dogs = append(dogs, &DogResolver{d, r.Db})
 	}
-	return &dogs
+	return &dogs, nil
+}
+
+// DogsConnection function required by graphql to return a page of the user's dogs
+func (r *UserResolver) DogsConnection(args connectionArgs) (*DogConnectionResolver, error) {
+	page, err := newPage(args.First, args.After, args.Last, args.Before)
+	if err != nil {
+		return nil, err
+	}
+	dogs, more, err := r.Db.GetDogsPageByOwner(r.u.ID, page)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	conn := &DogConnectionResolver{}
+	var cursors []string
+	for i := range dogs {
+		cursor := dogCursor(dogs[i].Name, dogs[i].ID)
+		cursors = append(cursors, cursor)
+		conn.edges = append(conn.edges, &DogEdgeResolver{cursor, &DogResolver{&dogs[i], r.Db}})
+	}
+	conn.pageInfo = newPageInfo(page, cursors, more)
+	return conn, nil
 }
 
 // JoinDate function required by graphql to return user's email
@@ -140,9 +190,10 @@ func (r *UserResolver) JoinDate() *graphql.Time {
 	return &r.u.JoinDate
 }
 
-// ProfileImageURL function required by graphql to return user's email
-func (r *UserResolver) ProfileImageURL() *string {
-	return &r.u.ProfileImageURL
+// ProfileImageURL function required by graphql to return user's picture at the requested size
+func (r *UserResolver) ProfileImageURL(args imageSizeArgs) *string {
+	url := images.SizedURL(r.u.ProfileImageURL, args.size())
+	return &url
 }
 
 // ID function required by graphql to return dogs's ID
@@ -166,53 +217,130 @@ func (r *DogResolver) Breed() *string {
 }
 
 // Owner function required by graphql to return dogs's User object
-func (r *DogResolver) Owner() *UserResolver {
-	if u := r.o; u != nil {
-		return &UserResolver{u, r.dogs, r.Db}
+func (r *DogResolver) Owner(ctx context.Context) (*UserResolver, error) {
+	u, err := loader.LoadUser(ctx, r.d.Owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
 	}
-	return nil
+	return &UserResolver{&u, nil, r.Db}, nil
 }
 
-// ProfileImageURL function required by graphql to return user's email
-func (r *DogResolver) ProfileImageURL() *string {
-	return &r.d.ProfileImageURL
+// ProfileImageURL function required by graphql to return dog's picture at the requested size
+func (r *DogResolver) ProfileImageURL(args imageSizeArgs) *string {
+	url := images.SizedURL(r.d.ProfileImageURL, args.size())
+	return &url
 }
 
 // GetDoggyDates function required by graphql query
-func (r *Resolver) GetDoggyDates() (*[]*DoggyDateResolver, error) {
-	dates, users, dogs, err := r.Db.GetAllDoggyDates()
+func (r *Resolver) GetDoggyDates(args struct {
+	Filter           *doggyDateFilter
+	OrderBy          *string
+	IncludeCancelled *bool
+	First            *int32
+	After            *string
+	Last             *int32
+	Before           *string
+}) (*DoggyDateConnectionResolver, error) {
+	page, err := newPage(args.First, args.After, args.Last, args.Before)
+	if err != nil {
+		return nil, err
+	}
+	page.Descending = args.OrderBy != nil && *args.OrderBy == "DATE_DESC"
+	filter := args.Filter.toStore()
+	filter.IncludeCancelled = args.IncludeCancelled != nil && *args.IncludeCancelled
+	dates, more, err := r.Db.GetDoggyDatesPage(page, filter)
 	if err != nil {
 		log.Println(err)
-		return &[]*DoggyDateResolver{{&types.Date{}, r.Db, &types.User{}, &map[graphql.ID]types.Dog{}}}, err
+		return nil, err
 	}
-	var ddr []*DoggyDateResolver
-	for _, v := range dates {
-		date := new(types.Date)
-		*date = v
-		u := users[v.User]
-		ddr = append(ddr, &DoggyDateResolver{date, r.Db, &u, &dogs})
+	conn := &DoggyDateConnectionResolver{}
+	var cursors []string
+	for i := range dates {
+		date := &dates[i]
+		cursor := encodeCursor(store.Cursor{Key: date.Date.Format(time.RFC3339Nano), ID: string(date.ID)})
+		cursors = append(cursors, cursor)
+		conn.edges = append(conn.edges, &DoggyDateEdgeResolver{cursor, &DoggyDateResolver{date, r.Db}})
 	}
+	conn.pageInfo = newPageInfo(page, cursors, more)
 	log.Println("Resolve: getDoggyDates graphql query")
-	return &ddr, nil
+	return conn, nil
 }
 
 // PlanDate graphql mutation
-func (r *Resolver) PlanDate(args *struct {
-	Date        graphql.Time
-	Description string
-	Dogs        []graphql.ID
-	Location    string
-	User        graphql.ID
+func (r *Resolver) PlanDate(ctx context.Context, args *struct {
+	Date                 graphql.Time
+	Description          string
+	Dogs                 []graphql.ID
+	Location             *string
+	User                 graphql.ID
+	Latitude             *float64
+	Longitude            *float64
+	PlaceName            *string
+	PlaceID              *graphql.ID
+	RequiresVaccinations *[]string
+	MatchedDog           *graphql.ID
 }) (*DoggyDateResolver, error) {
-	date, err := r.Db.InsertDoggyDate(args.Date, args.Description, args.Dogs, args.Location, args.User)
+	if err := auth.CanEdit(ctx, r.Db, "users", args.User); err != nil {
+		return nil, err
+	}
+	if err := auth.CanEditDogs(ctx, r.Db, args.Dogs); err != nil {
+		return nil, err
+	}
+	coords, err := dateCoordinates(args.Latitude, args.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	newDate := types.Date{
+		Date:        args.Date,
+		Description: args.Description,
+		Dogs:        args.Dogs,
+		User:        args.User,
+		Coordinates: coords,
+	}
+	if args.Location != nil {
+		newDate.Location = *args.Location
+	}
+	if args.PlaceName != nil {
+		newDate.PlaceName = *args.PlaceName
+	}
+	// A directory place fills in where the date is, the location text stays as a note
+	if args.PlaceID != nil {
+		p, err := r.datePlace(*args.PlaceID)
+		if err != nil {
+			return nil, err
+		}
+		newDate.Place = p.ID
+		newDate.Coordinates = &p.Coordinates
+		newDate.PlaceName = p.Name
+		if newDate.Location == "" {
+			newDate.Location = p.Name
+		}
+	}
+	if newDate.Location == "" {
+		return nil, errNoLocation
+	}
+	if args.RequiresVaccinations != nil {
+		newDate.RequiresVaccinations = dedupeStrings(*args.RequiresVaccinations)
+	}
+	if err := r.checkVaccinations(ctx, newDate.Dogs, newDate.RequiresVaccinations, newDate.Date); err != nil {
+		return nil, err
+	}
+	// A match seeds the date, the matched dog is invited along with planning it
+	var invited []graphql.ID
+	if args.MatchedDog != nil {
+		if err := r.checkMatched(newDate.Dogs, *args.MatchedDog); err != nil {
+			return nil, err
+		}
+		invited = append(invited, *args.MatchedDog)
+	}
+	date, err := r.Db.InsertDoggyDate(newDate, invited)
 	if err != nil {
 		log.Println(err)
 		return &DoggyDateResolver{}, err
 	}
-	dogMap, uMap, _ := r.Db.GetDogsByArray(args.Dogs)
-	u := uMap[date.User]
 	log.Println("Resolve: planDate graphql mutation")
-	return &DoggyDateResolver{&date, r.Db, &u, &dogMap}, err
+	return &DoggyDateResolver{&date, r.Db}, err
 }
 
 // ID function required by graphql to return DoggyDates's ID
@@ -230,19 +358,18 @@ func (r *DoggyDateResolver) Description() *string {
 	return &r.date.Description
 }
 
-// Dogs function required by graphql to return DoggyDates's ID
-func (r *DoggyDateResolver) Dogs() *[]*DogResolver {
-	var dogs []*DogResolver
-	var doga []types.Dog
-	for i := 0; i < len(r.u.Dogs); i++ {
-		doga = append(doga, (*r.dogMap)[r.u.Dogs[i]])
+// Dogs function required by graphql to return DoggyDates's dogs
+func (r *DoggyDateResolver) Dogs(ctx context.Context) (*[]*DogResolver, error) {
+	d, err := loader.LoadDogs(ctx, r.date.Dogs)
+	if err != nil {
+		log.Println(err)
+		return nil, err
 	}
-	for i := 0; i < len(r.date.Dogs); i++ {
-		d := (*r.dogMap)[r.date.Dogs[i]]
-		dogs = append(dogs, &DogResolver{&d, &doga, r.Db, r.u})
+	var dogs []*DogResolver
+	for i := 0; i < len(d); i++ {
+		dogs = append(dogs, &DogResolver{&d[i], r.Db})
 	}
-
-	return &dogs
+	return &dogs, nil
 }
 
 // Location function required by graphql to return DoggyDates's ID
@@ -250,12 +377,38 @@ func (r *DoggyDateResolver) Location() *string {
 	return &r.date.Location
 }
 
-// User function required by graphql to return DoggyDates's ID
-func (r *DoggyDateResolver) User() *UserResolver {
-	var dogs []types.Dog
-	for i := 0; i < len(r.u.Dogs); i++ {
-		d := (*r.dogMap)[r.u.Dogs[i]]
-		dogs = append(dogs, d)
+// Status function required by graphql to return DoggyDates's status
+func (r *DoggyDateResolver) Status() string {
+	return r.date.Status
+}
+
+// CancelReason function required by graphql to return why a DoggyDate was cancelled
+func (r *DoggyDateResolver) CancelReason() *string {
+	if r.date.Status != types.DateStatusCancelled {
+		return nil
+	}
+	return &r.date.CancelReason
+}
+
+// User function required by graphql to return DoggyDates's organizer
+func (r *DoggyDateResolver) User(ctx context.Context) (*UserResolver, error) {
+	u, err := loader.LoadUser(ctx, r.date.User)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &UserResolver{&u, nil, r.Db}, nil
+}
+
+// dedupeStrings drops repeated values, keeping the first of each
+func dedupeStrings(values []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
 	}
-	return &UserResolver{r.u, &dogs, r.Db}
+	return out
 }
@@ -0,0 +1,328 @@
+package gql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/store/memory"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// newTestHandler serves the schema over db behind the same middleware as server.go
+func newTestHandler(t *testing.T, db store.Store) http.Handler {
+	t.Helper()
+	s, err := ioutil.ReadFile("schema.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := graphql.MustParseSchema(string(s), &Resolver{Db: db})
+	return loader.Middleware(db)(auth.Middleware(db)(&relay.Handler{Schema: schema}))
+}
+
+// exec runs query as the holder of token, decoding data into out, and returns the error messages
+func exec(t *testing.T, h http.Handler, token string, query string, vars map[string]interface{}, out interface{}) []string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var res struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if out != nil && len(res.Data) > 0 {
+		if err := json.Unmarshal(res.Data, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var msgs []string
+	for _, e := range res.Errors {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// expectError fails unless msgs is exactly the one message want
+func expectError(t *testing.T, msgs []string, want string) {
+	t.Helper()
+	if len(msgs) != 1 || msgs[0] != want {
+		t.Errorf("errors = %q, want %q", msgs, want)
+	}
+}
+
+const createUser = `mutation($email: String!) {
+  createUser(name: "Ann", email: $email, password: "hunter22", userProfileImageURL: "",
+    dogName: "Kora", dogAge: 3, dogBreed: "Shiba", dogProfileImageURL: "") { id email dogs { name } }
+}`
+
+const login = `mutation($email: String!, $password: String!) {
+  login(email: $email, password: $password) { token user { id dogs { name } } }
+}`
+
+func TestCreateUserAndLogin(t *testing.T) {
+	h := newTestHandler(t, memory.New())
+
+	var created struct {
+		CreateUser struct {
+			ID    string
+			Email string
+			Dogs  []struct{ Name string }
+		}
+	}
+	if msgs := exec(t, h, "", createUser, map[string]interface{}{"email": "ann@example.com"}, &created); msgs != nil {
+		t.Fatal(msgs)
+	}
+	if u := created.CreateUser; u.ID == "" || u.Email != "ann@example.com" || len(u.Dogs) != 1 || u.Dogs[0].Name != "Kora" {
+		t.Fatalf("createUser = %+v", u)
+	}
+	msgs := exec(t, h, "", createUser, map[string]interface{}{"email": "ann@example.com"}, nil)
+	expectError(t, msgs, "Error: Email ann@example.com already exists")
+
+	for _, creds := range []map[string]interface{}{
+		{"email": "ann@example.com", "password": "wrong"},
+		{"email": "bob@example.com", "password": "hunter22"},
+	} {
+		msgs := exec(t, h, "", login, creds, nil)
+		expectError(t, msgs, errInvalidCredentials.Error())
+	}
+
+	var in struct {
+		Login struct {
+			Token string
+			User  struct {
+				ID   string
+				Dogs []struct{ Name string }
+			}
+		}
+	}
+	if msgs := exec(t, h, "", login, map[string]interface{}{"email": "ann@example.com", "password": "hunter22"}, &in); msgs != nil {
+		t.Fatal(msgs)
+	}
+	if in.Login.Token == "" || in.Login.User.ID != created.CreateUser.ID || len(in.Login.User.Dogs) != 1 {
+		t.Fatalf("login = %+v", in.Login)
+	}
+
+	var me struct{ Me struct{ Email string } }
+	if msgs := exec(t, h, in.Login.Token, `{ me { email } }`, nil, &me); msgs != nil || me.Me.Email != "ann@example.com" {
+		t.Errorf("me = %+v, errors %q", me.Me, msgs)
+	}
+	expectError(t, exec(t, h, "", `{ me { email } }`, nil, nil), auth.ErrUnauthenticated.Error())
+}
+
+func TestNotFound(t *testing.T) {
+	db := memory.New()
+	h := newTestHandler(t, db)
+	u, d, err := db.InsertUserDog("Ann", "ann@example.com", "hash", "", "Kora", 3, "Shiba", "", types.DogProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := uuid.NewV4()
+	missing := id.String()
+
+	var found struct {
+		User struct{ Name string }
+		Dog  struct{ Name string }
+	}
+	msgs := exec(t, h, "", `query($u: ID!, $d: ID!) { user(id: $u) { name } dog(id: $d) { name } }`,
+		map[string]interface{}{"u": u.ID, "d": d.ID}, &found)
+	if msgs != nil || found.User.Name != "Ann" || found.Dog.Name != "Kora" {
+		t.Fatalf("found = %+v, errors %q", found, msgs)
+	}
+
+	msgs = exec(t, h, "", `query($d: ID!) { dog(id: $d) { name } }`, map[string]interface{}{"d": missing}, nil)
+	expectError(t, msgs, "Error: Dog "+missing+" does not exist")
+	msgs = exec(t, h, "", `query($u: ID!) { user(id: $u) { id name } }`, map[string]interface{}{"u": missing}, nil)
+	expectError(t, msgs, "Error: User "+missing+" does not exist")
+
+	if msgs := exec(t, h, "", `{ user(id: "not-a-uuid") { id } }`, nil, nil); len(msgs) != 1 {
+		t.Errorf("malformed id errors = %q", msgs)
+	}
+}
+
+type pageInfo struct {
+	StartCursor     *string
+	EndCursor       *string
+	HasNextPage     bool
+	HasPreviousPage bool
+}
+
+const datesPage = `query($first: Int, $after: String, $last: Int, $before: String) {
+  getDoggyDates(first: $first, after: $after, last: $last, before: $before) {
+    edges { cursor node { description } }
+    pageInfo { startCursor endCursor hasNextPage hasPreviousPage }
+  }
+}`
+
+type datesResult struct {
+	GetDoggyDates struct {
+		Edges []struct {
+			Cursor string
+			Node   struct{ Description string }
+		}
+		PageInfo pageInfo
+	}
+}
+
+func (r datesResult) descriptions() []string {
+	var out []string
+	for _, e := range r.GetDoggyDates.Edges {
+		out = append(out, e.Node.Description)
+	}
+	return out
+}
+
+func TestGetDoggyDatesPagination(t *testing.T) {
+	db := memory.New()
+	h := newTestHandler(t, db)
+	u, d, err := db.InsertUserDog("Ann", "ann@example.com", "hash", "", "Kora", 3, "Shiba", "", types.DogProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2030, 6, 1, 10, 0, 0, 0, time.UTC)
+	// Inserted out of order, two on the same day so the id breaks the tie
+	for _, i := range []int{3, 0, 4, 1, 2} {
+		when := day.AddDate(0, 0, i)
+		if i == 2 {
+			when = day.AddDate(0, 0, 1)
+		}
+		_, err := db.InsertDoggyDate(types.Date{
+			Date:        graphql.Time{Time: when},
+			Description: string(rune('a' + i)),
+			Dogs:        []graphql.ID{d.ID},
+			Location:    "Park",
+			User:        u.ID,
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := func(pageInfo) map[string]interface{} { return map[string]interface{}{"first": 2} }
+	forward := func(p pageInfo) map[string]interface{} {
+		return map[string]interface{}{"first": 2, "after": *p.EndCursor}
+	}
+	backward := func(p pageInfo) map[string]interface{} {
+		return map[string]interface{}{"last": 2, "before": *p.StartCursor}
+	}
+	tests := []struct {
+		name     string
+		vars     func(prev pageInfo) map[string]interface{}
+		want     int
+		next     bool
+		previous bool
+	}{
+		{"first page", start, 2, true, false},
+		{"second page", forward, 2, true, true},
+		{"last page", forward, 1, false, true},
+		{"back from the end", backward, 2, true, true},
+	}
+	var all []string
+	var prev pageInfo
+	for _, tt := range tests {
+		var res datesResult
+		if msgs := exec(t, h, "", datesPage, tt.vars(prev), &res); msgs != nil {
+			t.Fatalf("%s: %q", tt.name, msgs)
+		}
+		p := res.GetDoggyDates.PageInfo
+		if len(res.GetDoggyDates.Edges) != tt.want || p.HasNextPage != tt.next || p.HasPreviousPage != tt.previous {
+			t.Fatalf("%s: %d edges, pageInfo %+v", tt.name, len(res.GetDoggyDates.Edges), p)
+		}
+		if tt.name != "back from the end" {
+			all = append(all, res.descriptions()...)
+		} else if got := res.descriptions(); len(all) == 5 && (got[0] != all[2] || got[1] != all[3]) {
+			t.Errorf("%s = %v, want %v", tt.name, got, all[2:4])
+		}
+		prev = p
+	}
+	if len(all) != 5 || all[0] != "a" || all[3] != "d" || all[4] != "e" {
+		t.Errorf("dates in order = %v", all)
+	}
+	// b and c share a day, keyset order still puts each on exactly one page
+	if (all[1] != "b" || all[2] != "c") && (all[1] != "c" || all[2] != "b") {
+		t.Errorf("same day dates = %v", all[1:3])
+	}
+
+	errTests := []struct {
+		vars map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"first": 1, "last": 1}, "Error: Cannot paginate with both first and last"},
+		{map[string]interface{}{"first": 1, "before": *prev.StartCursor}, errMixedPage.Error()},
+		{map[string]interface{}{"last": 1, "after": *prev.EndCursor}, errMixedPage.Error()},
+		{map[string]interface{}{"after": *prev.EndCursor, "before": *prev.StartCursor}, errMixedPage.Error()},
+		{map[string]interface{}{"first": maxPageSize + 1}, errPageSize.Error()},
+		{map[string]interface{}{"first": -1}, errPageSize.Error()},
+		{map[string]interface{}{"after": "not a cursor!"}, errInvalidCursor.Error()},
+	}
+	for _, tt := range errTests {
+		expectError(t, exec(t, h, "", datesPage, tt.vars, nil), tt.want)
+	}
+}
+
+func TestDogsConnectionPagination(t *testing.T) {
+	db := memory.New()
+	h := newTestHandler(t, db)
+	u, _, err := db.InsertUserDog("Ann", "ann@example.com", "hash", "", "Kora", 3, "Shiba", "", types.DogProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Bo", "Pip"} {
+		if _, err := db.InsertDog(u.ID, name, 2, "Pug", "", types.DogProfile{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := `query($u: ID!, $after: String) {
+	  user(id: $u) { dogsConnection(first: 2, after: $after) {
+	    edges { node { name } }
+	    pageInfo { startCursor endCursor hasNextPage hasPreviousPage }
+	  } }
+	}`
+	var names []string
+	var after interface{}
+	for page := 0; page < 2; page++ {
+		var res struct {
+			User struct {
+				DogsConnection struct {
+					Edges    []struct{ Node struct{ Name string } }
+					PageInfo pageInfo
+				}
+			}
+		}
+		if msgs := exec(t, h, "", query, map[string]interface{}{"u": u.ID, "after": after}, &res); msgs != nil {
+			t.Fatal(msgs)
+		}
+		conn := res.User.DogsConnection
+		for _, e := range conn.Edges {
+			names = append(names, e.Node.Name)
+		}
+		if conn.PageInfo.HasNextPage != (page == 0) || conn.PageInfo.HasPreviousPage != (page == 1) {
+			t.Errorf("page %d pageInfo = %+v", page, conn.PageInfo)
+		}
+		after = *conn.PageInfo.EndCursor
+	}
+	want := []string{"Bo", "Kora", "Pip"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Errorf("dogs by name = %v, want %v", names, want)
+	}
+}
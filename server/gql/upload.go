@@ -0,0 +1,168 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/images"
+)
+
+// Upload is the Upload scalar, a file sent alongside the query in a multipart request
+type Upload struct {
+	File        multipart.File
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+// ImplementsGraphQLType maps Upload to the Upload scalar
+func (Upload) ImplementsGraphQLType(name string) bool {
+	return name == "Upload"
+}
+
+// UnmarshalGraphQL accepts the files MultipartHandler puts into the variables
+func (u *Upload) UnmarshalGraphQL(input interface{}) error {
+	up, ok := input.(*Upload)
+	if !ok {
+		return errors.New("Error: Upload variables must be sent as files in a multipart request")
+	}
+	*u = *up
+	return nil
+}
+
+// request is one GraphQL operation in a request body
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// MultipartHandler serves GraphQL multipart requests, as described by
+// https://github.com/jaydenseric/graphql-multipart-request-spec, and passes every
+// other request on to next
+func MultipartHandler(schema *graphql.Schema, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Room for one image plus the operations and multipart framing
+		r.Body = http.MaxBytesReader(w, r.Body, images.MaxBytes+1<<20)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		var operations interface{}
+		if err := json.Unmarshal([]byte(r.FormValue("operations")), &operations); err != nil {
+			http.Error(w, "Bad request: invalid operations field", http.StatusBadRequest)
+			return
+		}
+		var fileMap map[string][]string
+		if err := json.Unmarshal([]byte(r.FormValue("map")), &fileMap); err != nil {
+			http.Error(w, "Bad request: invalid map field", http.StatusBadRequest)
+			return
+		}
+		for field, paths := range fileMap {
+			headers := r.MultipartForm.File[field]
+			if len(headers) == 0 {
+				http.Error(w, fmt.Sprintf("Bad request: missing file %s", field), http.StatusBadRequest)
+				return
+			}
+			f, err := headers[0].Open()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer f.Close()
+			upload := &Upload{f, headers[0].Filename, headers[0].Header.Get("Content-Type"), headers[0].Size}
+			for _, path := range paths {
+				if err := setPath(operations, strings.Split(path, "."), upload); err != nil {
+					http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		// operations is either one request or a batch of them
+		var responses []*graphql.Response
+		batch, isBatch := operations.([]interface{})
+		if !isBatch {
+			batch = []interface{}{operations}
+		}
+		for _, op := range batch {
+			req, err := toRequest(op)
+			if err != nil {
+				http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			responses = append(responses, schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables))
+		}
+		var out interface{} = responses
+		if !isBatch {
+			out = responses[0]
+		}
+		responseJSON, err := json.Marshal(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(responseJSON)
+	})
+}
+
+// toRequest converts a decoded operation whose variables may hold uploads
+func toRequest(op interface{}) (request, error) {
+	m, ok := op.(map[string]interface{})
+	if !ok {
+		return request{}, errors.New("operation must be an object")
+	}
+	var req request
+	req.Query, _ = m["query"].(string)
+	req.OperationName, _ = m["operationName"].(string)
+	if v, ok := m["variables"]; ok && v != nil {
+		if req.Variables, ok = v.(map[string]interface{}); !ok {
+			return request{}, errors.New("variables must be an object")
+		}
+	}
+	return req, nil
+}
+
+// setPath replaces the value at an object path like variables.files.0 with upload
+func setPath(root interface{}, path []string, upload *Upload) error {
+	if len(path) == 0 {
+		return errors.New("empty map path")
+	}
+	key := path[0]
+	switch node := root.(type) {
+	case map[string]interface{}:
+		if _, ok := node[key]; !ok {
+			return fmt.Errorf("map path %s does not exist", key)
+		}
+		if len(path) == 1 {
+			node[key] = upload
+			return nil
+		}
+		return setPath(node[key], path[1:], upload)
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(node) {
+			return fmt.Errorf("map path index %s is out of range", key)
+		}
+		if len(path) == 1 {
+			node[i] = upload
+			return nil
+		}
+		return setPath(node[i], path[1:], upload)
+	default:
+		return fmt.Errorf("map path %s does not exist", key)
+	}
+}
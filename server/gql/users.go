@@ -0,0 +1,114 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"log"
+	"math"
+)
+
+// homePrecision keeps homes to 1/100 of a degree, about 1km, so a household is never stored
+// or compared more precisely than that
+const homePrecision = 100
+
+// UpdateUser graphql mutation, edits the logged in user's profile
+func (r *Resolver) UpdateUser(ctx context.Context, args *struct {
+	Name            *string
+	Email           *string
+	ProfileImageURL *string
+	Latitude        *float64
+	Longitude       *float64
+}) (*UserResolver, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if args.Name != nil && *args.Name == "" {
+		return nil, errors.New("Error: Name cannot be empty")
+	}
+	home, err := dateCoordinates(args.Latitude, args.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	if home != nil {
+		home.Latitude = math.Round(home.Latitude*homePrecision) / homePrecision
+		home.Longitude = math.Round(home.Longitude*homePrecision) / homePrecision
+	}
+	if args.Email != nil && *args.Email != v.User.Email {
+		if exists, _ := r.Db.CheckEmailExists(*args.Email); exists {
+			log.Printf("Error: Email %s already exists", *args.Email)
+			return nil, fmt.Errorf("Error: Email %s already exists", *args.Email)
+		}
+	}
+	user, err := r.Db.UpdateUser(v.User.ID, args.Name, args.Email, args.ProfileImageURL, home)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	_, dogs, err := r.Db.GetUserByEmail(user.Email)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: updateUser graphql mutation")
+	return &UserResolver{&user, &dogs, r.Db}, nil
+}
+
+// DeleteAccount graphql mutation, removes the logged in user, their dogs and doggy dates
+func (r *Resolver) DeleteAccount(ctx context.Context, args struct{ Password string }) (bool, error) {
+	v, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, hash, err := r.Db.GetUserCredentials(v.User.Email)
+	if err != nil || !auth.CheckPassword(hash, args.Password) {
+		log.Printf("Error: Failed deleteAccount for %s", v.User.Email)
+		return false, errInvalidCredentials
+	}
+	objs, err := r.Db.DeleteUser(v.User.ID)
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+	r.deleteObjects(ctx, objs)
+	log.Println("Resolve: deleteAccount graphql mutation")
+	return true, nil
+}
+
+// home returns where the user lives, only visible to the user themselves
+func (r *UserResolver) home(ctx context.Context) (*types.Coordinates, error) {
+	if v, ok := auth.ViewerFromContext(ctx); !ok || v.User.ID != r.u.ID {
+		return nil, nil
+	}
+	if r.u.Home != nil {
+		return r.u.Home, nil
+	}
+	u, err := loader.LoadUser(ctx, r.u.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return u.Home, nil
+}
+
+// Latitude function required by graphql to return where the user lives, only visible to the user themselves
+func (r *UserResolver) Latitude(ctx context.Context) (*float64, error) {
+	home, err := r.home(ctx)
+	if home == nil {
+		return nil, err
+	}
+	return &home.Latitude, nil
+}
+
+// Longitude function required by graphql to return where the user lives, only visible to the user themselves
+func (r *UserResolver) Longitude(ctx context.Context) (*float64, error) {
+	home, err := r.home(ctx)
+	if home == nil {
+		return nil, err
+	}
+	return &home.Longitude, nil
+}
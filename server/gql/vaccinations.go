@@ -0,0 +1,292 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
+	"github.com/raymondvooo/doggy-date-app/server/images"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	"github.com/raymondvooo/doggy-date-app/server/vaccines"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCertificateBytes is the largest certificate accepted, the same as an image upload
+const maxCertificateBytes = images.MaxBytes
+
+// certificateExts maps the accepted certificate content types to their extension
+var certificateExts = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+var (
+	errCertificate         = errors.New("Error: certificate must be a PDF, JPEG, PNG or WebP")
+	errCertificateSize     = fmt.Errorf("Error: certificate must be at most %d MB", maxCertificateBytes>>20)
+	errVaccinationDates    = errors.New("Error: expiresOn must be after administeredOn")
+	errVaccinationFuture   = errors.New("Error: administeredOn cannot be in the future")
+	errVaccinationNotFound = errors.New("Error: Vaccination not found")
+)
+
+// VaccinationResolver resolves a dog's vaccine record
+type VaccinationResolver struct {
+	v     *types.Vaccination
+	owner graphql.ID
+}
+
+// ID function required by graphql to return vaccination's ID
+func (r *VaccinationResolver) ID() graphql.ID {
+	return r.v.ID
+}
+
+// Vaccine function required by graphql to return the vaccine given
+func (r *VaccinationResolver) Vaccine() string {
+	return r.v.Vaccine
+}
+
+// AdministeredOn function required by graphql to return the day the vaccine was given
+func (r *VaccinationResolver) AdministeredOn() graphql.Time {
+	return r.v.AdministeredOn
+}
+
+// ExpiresOn function required by graphql to return the day the vaccine lapses
+func (r *VaccinationResolver) ExpiresOn() *graphql.Time {
+	return r.v.ExpiresOn
+}
+
+// Status function required by graphql to return whether the record is current today
+func (r *VaccinationResolver) Status() string {
+	return vaccines.RecordStatus(*r.v, time.Now())
+}
+
+// CertificateURL function required by graphql to return where the owner can download the
+// uploaded certificate, only the dog's owner can see it
+func (r *VaccinationResolver) CertificateURL(ctx context.Context) *string {
+	v, ok := auth.ViewerFromContext(ctx)
+	if r.v.CertificateKey == "" || !ok || v.User.ID != r.owner {
+		return nil
+	}
+	url := CertificatePath(r.v.Dog, r.v.ID)
+	return &url
+}
+
+// CertificatePath is the route serving a vaccination's certificate to the dog's owner
+func CertificatePath(dogID graphql.ID, id graphql.ID) string {
+	return fmt.Sprintf("/dog/%s/certificates/%s", dogID, id)
+}
+
+// CreatedAt function required by graphql to return when the record was added
+func (r *VaccinationResolver) CreatedAt() graphql.Time {
+	return r.v.CreatedAt
+}
+
+// Vaccinations function required by graphql to return dog's vaccine records
+func (r *DogResolver) Vaccinations(ctx context.Context) ([]*VaccinationResolver, error) {
+	records, err := loader.LoadVaccinations(ctx, r.d.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	res := []*VaccinationResolver{}
+	for i := range records {
+		res = append(res, &VaccinationResolver{&records[i], r.d.Owner})
+	}
+	return res, nil
+}
+
+// VaccinationStatus function required by graphql to return whether dog's vaccines are up to date
+func (r *DogResolver) VaccinationStatus(ctx context.Context) (*string, error) {
+	records, err := loader.LoadVaccinations(ctx, r.d.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return vaccines.Status(records, time.Now()), nil
+}
+
+// readCertificate reads an uploaded certificate and detects its content type
+func readCertificate(f io.Reader) ([]byte, string, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(f, maxCertificateBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxCertificateBytes {
+		return nil, "", errCertificateSize
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := certificateExts[contentType]; !ok {
+		return nil, "", errCertificate
+	}
+	return data, contentType, nil
+}
+
+// AddVaccination graphql mutation, records a vaccine given to a dog with an optional certificate
+func (r *Resolver) AddVaccination(ctx context.Context, args struct {
+	DogID          graphql.ID
+	Vaccine        string
+	AdministeredOn graphql.Time
+	ExpiresOn      *graphql.Time
+	Certificate    *Upload
+}) (*VaccinationResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	// Records are kept by day
+	day := func(t time.Time) time.Time { return t.UTC().Truncate(24 * time.Hour) }
+	vid, _ := uuid.NewV1()
+	v := types.Vaccination{
+		ID:             graphql.ID(vid.String()),
+		Dog:            args.DogID,
+		Vaccine:        args.Vaccine,
+		AdministeredOn: graphql.Time{Time: day(args.AdministeredOn.Time)},
+		CreatedAt:      graphql.Time{Time: time.Now()},
+	}
+	if v.AdministeredOn.Time.After(time.Now()) {
+		return nil, errVaccinationFuture
+	}
+	if args.ExpiresOn != nil {
+		v.ExpiresOn = &graphql.Time{Time: day(args.ExpiresOn.Time)}
+		if !v.ExpiresOn.Time.After(v.AdministeredOn.Time) {
+			return nil, errVaccinationDates
+		}
+	}
+	if args.Certificate != nil {
+		data, contentType, err := readCertificate(args.Certificate.File)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		key := fmt.Sprintf("certificates/%s/%s%s", args.DogID, v.ID, certificateExts[contentType])
+		err = r.Documents.Put(ctx, key, bytes.NewReader(data), int64(len(data)), storage.PutOptions{
+			ContentType:        contentType,
+			ContentDisposition: "inline",
+		})
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		v.CertificateKey, v.CertificateContentType = key, contentType
+	}
+	saved, err := r.Db.InsertVaccination(v)
+	if err != nil {
+		log.Println(err)
+		if v.CertificateKey != "" {
+			r.Documents.Delete(ctx, v.CertificateKey)
+		}
+		return nil, err
+	}
+	dog, err := loader.LoadDog(ctx, args.DogID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Println("Resolve: addVaccination graphql mutation")
+	return &VaccinationResolver{&saved, dog.Owner}, nil
+}
+
+// RemoveVaccination graphql mutation, deletes a record and its certificate
+func (r *Resolver) RemoveVaccination(ctx context.Context, args struct {
+	DogID         graphql.ID
+	VaccinationID graphql.ID
+}) (*DogResolver, error) {
+	if err := auth.CanEdit(ctx, r.Db, "dogs", args.DogID); err != nil {
+		return nil, err
+	}
+	v, err := r.Db.DeleteVaccination(args.DogID, args.VaccinationID)
+	if err != nil {
+		log.Println(err)
+		return nil, errVaccinationNotFound
+	}
+	if v.CertificateKey != "" {
+		if err := r.Documents.Delete(ctx, v.CertificateKey); err != nil {
+			log.Println("Delete certificate Error: ", err)
+		}
+	}
+	log.Println("Resolve: removeVaccination graphql mutation")
+	return r.Dog(struct{ ID graphql.ID }{args.DogID})
+}
+
+// missingVaccinations lists the required vaccines one dog does not have current
+type missingVaccinations struct {
+	dog      types.Dog
+	vaccines []string
+}
+
+// vaccinationsError is returned when dogs joining a date lack its required vaccinations,
+// the extensions list the missing vaccines per dog
+type vaccinationsError struct {
+	dogs []missingVaccinations
+}
+
+func (e *vaccinationsError) Error() string {
+	msg := "Error: Dogs are missing vaccinations required by the date:"
+	for i, m := range e.dogs {
+		if i > 0 {
+			msg += ";"
+		}
+		msg += fmt.Sprintf(" %s needs %s", m.dog.Name, strings.Join(m.vaccines, ", "))
+	}
+	return msg
+}
+
+// Extensions function required by graphql-go to add the missing vaccines to the error response
+func (e *vaccinationsError) Extensions() map[string]interface{} {
+	dogs := []map[string]interface{}{}
+	for _, m := range e.dogs {
+		dogs = append(dogs, map[string]interface{}{
+			"dogId":   m.dog.ID,
+			"name":    m.dog.Name,
+			"missing": m.vaccines,
+		})
+	}
+	return map[string]interface{}{"code": "MISSING_VACCINATIONS", "dogs": dogs}
+}
+
+// checkVaccinations returns a vaccinationsError unless every dog has the required
+// vaccines current on the day of the date
+func (r *Resolver) checkVaccinations(ctx context.Context, dogIds []graphql.ID, required []string, on graphql.Time) error {
+	if len(required) == 0 || len(dogIds) == 0 {
+		return nil
+	}
+	records, err := r.Db.GetVaccinations(dogIds)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	dogs, err := r.Db.GetDogsByIDs(dogIds)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	e := &vaccinationsError{}
+	for _, id := range dogIds {
+		if missing := vaccines.Missing(records[id], required, on.Time); len(missing) > 0 {
+			e.dogs = append(e.dogs, missingVaccinations{dogs[id], missing})
+		}
+	}
+	if len(e.dogs) > 0 {
+		return e
+	}
+	return nil
+}
+
+// RequiresVaccinations function required by graphql to return the vaccines dogs need to join the date
+func (r *DoggyDateResolver) RequiresVaccinations() []string {
+	if r.date.RequiresVaccinations == nil {
+		return []string{}
+	}
+	return r.date.RequiresVaccinations
+}
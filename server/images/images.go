@@ -0,0 +1,171 @@
+// Package images validates uploaded pictures and re-encodes them into the sizes the
+// app displays. Re-encoding drops all metadata, including EXIF GPS coordinates.
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// MaxBytes is the largest upload accepted
+const MaxBytes = 10 << 20
+
+// MaxPixels guards against small files that decode into huge images
+const MaxPixels = 40000000
+
+// Errors returned for uploads that are rejected
+var (
+	ErrTooLarge    = errors.New("Error: image must be smaller than 10MB")
+	ErrUnsupported = errors.New("Error: image must be a JPEG, PNG or WebP")
+	ErrTooManyPx   = errors.New("Error: image dimensions are too large")
+)
+
+// Size names one of the generated variants
+type Size string
+
+// Generated sizes, each fits inside a square of its edge length
+const (
+	Thumbnail Size = "thumbnail"
+	Card      Size = "card"
+	Full      Size = "full"
+)
+
+// Sizes lists every variant in the order they are generated
+var Sizes = []Size{Full, Card, Thumbnail}
+
+var edges = map[Size]int{
+	Thumbnail: 160,
+	Card:      640,
+	Full:      1600,
+}
+
+// Variant is one encoded size of an upload
+type Variant struct {
+	Size        Size
+	ContentType string
+	Ext         string
+	Data        []byte
+}
+
+// Process reads an upload, checks it really is a supported image and returns it
+// re-encoded at every size in Sizes
+func Process(r io.Reader) ([]Variant, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Trust the bytes rather than the client's Content-Type
+	var decode func(io.Reader) (image.Image, error)
+	var decodeConfig func(io.Reader) (image.Config, error)
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		decode, decodeConfig = jpeg.Decode, jpeg.DecodeConfig
+	case "image/png":
+		decode, decodeConfig = png.Decode, png.DecodeConfig
+	case "image/webp":
+		decode, decodeConfig = webp.Decode, webp.DecodeConfig
+	default:
+		return nil, ErrUnsupported
+	}
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPx
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	// PNGs keep their transparency, everything else becomes a JPEG
+	encode, contentType, ext := encodeJPEG, "image/jpeg", ".jpg"
+	if o, ok := img.(interface{ Opaque() bool }); ok && !o.Opaque() {
+		encode, contentType, ext = png.Encode, "image/png", ".png"
+	}
+
+	// The pixels are re-encoded without metadata, so apply the camera's rotation first.
+	// The square bounding boxes make it safe to shrink before rotating.
+	img = orient(fit(img, edges[Full]), orientation(data))
+
+	var variants []Variant
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := encode(&buf, fit(img, edges[size])); err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{size, contentType, ext, buf.Bytes()})
+	}
+	return variants, nil
+}
+
+// ContentKey names an upload after its owner and the hash of its full size image,
+// e.g. dogs/<dog id>/<sha256>, so different pictures never share a key
+func ContentKey(entityType string, entityID string, full Variant) string {
+	return fmt.Sprintf("%s/%s/%x", entityType, entityID, sha256.Sum256(full.Data))
+}
+
+// Key names the object a variant is stored under, e.g. <base>.thumbnail.jpg
+func Key(base string, v Variant) string {
+	return SizeKey(base, v.Size, v.Ext)
+}
+
+// SizeKey names the object one size of an upload is stored under
+func SizeKey(base string, size Size, ext string) string {
+	return base + "." + string(size) + ext
+}
+
+// SizedURL turns the URL of a full size image into the URL of another size. URLs
+// saved before sizes were generated have no variants and are returned unchanged.
+func SizedURL(url string, size Size) string {
+	full := "." + string(Full) + "."
+	i := strings.LastIndex(url, full)
+	if i < 0 || size == Full || size == "" {
+		return url
+	}
+	return url[:i] + "." + string(size) + "." + url[i+len(full):]
+}
+
+func encodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+// fit scales img down to fit inside an edge by edge square, smaller images are kept as is
+func fit(img image.Image, edge int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= edge && h <= edge {
+		return img
+	}
+	if w >= h {
+		w, h = edge, h*edge/w
+	} else {
+		w, h = w*edge/h, edge
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
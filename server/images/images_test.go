@@ -0,0 +1,234 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// corners returns an opaque w by h image with a distinct color in each corner
+func corners(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+	img.Set(0, 0, red)
+	img.Set(w-1, 0, green)
+	img.Set(0, h-1, blue)
+	img.Set(w-1, h-1, white)
+	return img
+}
+
+// exifSegment is an APP1 segment holding a TIFF header whose first IFD sets orientation o
+func exifSegment(order binary.ByteOrder, o uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)       // first IFD
+	order.PutUint16(tiff[8:], 1)       // one entry
+	order.PutUint16(tiff[10:], 0x0112) // orientation
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], o)
+	body := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(body)+2))
+	return append(seg, body...)
+}
+
+// withSegments inserts segments right after the SOI marker of a JPEG
+func withSegments(jpg []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, jpg[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, jpg[2:]...)
+}
+
+func encodedJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodedPNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrient(t *testing.T) {
+	src := corners(3, 2)
+	tests := []struct {
+		o    int
+		w, h int
+		// colors expected at the top left, top right, bottom left and bottom right
+		want [4]color.RGBA
+	}{
+		{1, 3, 2, [4]color.RGBA{red, green, blue, white}},
+		{2, 3, 2, [4]color.RGBA{green, red, white, blue}},
+		{3, 3, 2, [4]color.RGBA{white, blue, green, red}},
+		{4, 3, 2, [4]color.RGBA{blue, white, red, green}},
+		{5, 2, 3, [4]color.RGBA{red, blue, green, white}},
+		{6, 2, 3, [4]color.RGBA{blue, red, white, green}},
+		{7, 2, 3, [4]color.RGBA{white, green, blue, red}},
+		{8, 2, 3, [4]color.RGBA{green, white, red, blue}},
+		{0, 3, 2, [4]color.RGBA{red, green, blue, white}},
+		{9, 3, 2, [4]color.RGBA{red, green, blue, white}},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.o)
+		b := got.Bounds()
+		if b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orient(%d) is %dx%d, want %dx%d", tt.o, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		at := []image.Point{{0, 0}, {tt.w - 1, 0}, {0, tt.h - 1}, {tt.w - 1, tt.h - 1}}
+		for i, p := range at {
+			if c := color.RGBAModel.Convert(got.At(b.Min.X+p.X, b.Min.Y+p.Y)); c != tt.want[i] {
+				t.Errorf("orient(%d) at %v = %v, want %v", tt.o, p, c, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	jpg := encodedJPEG(t, corners(4, 2))
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x04, 'J', 'F'}
+	type test struct {
+		name string
+		data []byte
+		want int
+	}
+	tests := []test{
+		{"no exif", jpg, 1},
+		{"png", encodedPNG(t, corners(4, 2)), 1},
+		{"empty", nil, 1},
+		{"out of range", withSegments(jpg, exifSegment(binary.BigEndian, 9)), 1},
+		{"after app0", withSegments(jpg, app0, exifSegment(binary.LittleEndian, 6)), 6},
+		{"app1 without exif", withSegments(jpg, []byte{0xFF, 0xE1, 0x00, 0x06, 'h', 't', 't', 'p'}), 1},
+		{"segment length too short", withSegments(jpg, []byte{0xFF, 0xE1, 0x00, 0x01}), 1},
+		{"segment length past the end", append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}, "Exif\x00\x00MM"...), 1},
+		{"bad byte order", withSegments(jpg, append([]byte{0xFF, 0xE1, 0x00, 0x10}, "Exif\x00\x00XX\x00\x2a\x00\x00\x00\x08"...)), 1},
+		{"ifd past the end", withSegments(jpg, append([]byte{0xFF, 0xE1, 0x00, 0x10}, "Exif\x00\x00MM\x00\x2a\xff\xff\xff\xff"...)), 1},
+	}
+	for o := uint16(1); o <= 8; o++ {
+		tests = append(tests,
+			test{"big endian", withSegments(jpg, exifSegment(binary.BigEndian, o)), int(o)},
+			test{"little endian", withSegments(jpg, exifSegment(binary.LittleEndian, o)), int(o)},
+		)
+	}
+	for _, tt := range tests {
+		if got := orientation(tt.data); got != tt.want {
+			t.Errorf("%s: orientation = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrientationMalformed(t *testing.T) {
+	jpg := withSegments(encodedJPEG(t, corners(4, 2)), exifSegment(binary.BigEndian, 6))
+	// Every truncation of a valid header
+	for n := 0; n <= 64 && n <= len(jpg); n++ {
+		if o := orientation(jpg[:n]); o < 1 || o > 8 {
+			t.Errorf("orientation of %d bytes = %d", n, o)
+		}
+	}
+	// Garbage after the SOI marker and APP1 header
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		data := make([]byte, 4+rnd.Intn(64))
+		rnd.Read(data)
+		copy(data, []byte{0xFF, 0xD8, 0xFF, 0xE1})
+		if o := orientation(data); o < 1 || o > 8 {
+			t.Errorf("orientation of %x = %d", data, o)
+		}
+		if o := tiffOrientation(data[4:]); o < 1 || o > 8 {
+			t.Errorf("tiffOrientation of %x = %d", data[4:], o)
+		}
+	}
+}
+
+// hugePNG is a PNG whose header claims w by h pixels, only its header is valid
+func hugePNG(t *testing.T, w, h uint32) []byte {
+	t.Helper()
+	data := encodedPNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	// The IHDR chunk follows the 8 byte signature: length, type, width, height, ..., CRC
+	binary.BigEndian.PutUint32(data[16:], w)
+	binary.BigEndian.PutUint32(data[20:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestProcess(t *testing.T) {
+	opaque := encodedPNG(t, corners(2000, 1000))
+	transparent := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	rotated := withSegments(encodedJPEG(t, corners(40, 20)), exifSegment(binary.BigEndian, 6))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		err         error
+		contentType string
+		// full size dimensions, the other sizes are checked to fit their edge
+		w, h int
+	}{
+		{"opaque png", opaque, nil, "image/jpeg", 1600, 800},
+		{"transparent png", encodedPNG(t, transparent), nil, "image/png", 10, 10},
+		{"rotated jpeg", rotated, nil, "image/jpeg", 20, 40},
+		{"html", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), ErrUnsupported, "", 0, 0},
+		{"png signature with garbage", append([]byte("\x89PNG\r\n\x1a\n"), "<html></html>"...), ErrUnsupported, "", 0, 0},
+		{"too many pixels", hugePNG(t, 10000, 10000), ErrTooManyPx, "", 0, 0},
+		{"too large", make([]byte, MaxBytes+1), ErrTooLarge, "", 0, 0},
+	}
+	for _, tt := range tests {
+		variants, err := Process(bytes.NewReader(tt.data))
+		if err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(variants) != len(Sizes) {
+			t.Fatalf("%s: %d variants, want %d", tt.name, len(variants), len(Sizes))
+		}
+		for i, v := range variants {
+			if v.Size != Sizes[i] || v.ContentType != tt.contentType {
+				t.Errorf("%s: variant %d is %s %s", tt.name, i, v.Size, v.ContentType)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(v.Data))
+			if err != nil {
+				t.Errorf("%s: %s does not decode: %v", tt.name, v.Size, err)
+				continue
+			}
+			if v.Size == Full && (cfg.Width != tt.w || cfg.Height != tt.h) {
+				t.Errorf("%s: full size is %dx%d, want %dx%d", tt.name, cfg.Width, cfg.Height, tt.w, tt.h)
+			}
+			if cfg.Width > edges[v.Size] || cfg.Height > edges[v.Size] {
+				t.Errorf("%s: %s is %dx%d", tt.name, v.Size, cfg.Width, cfg.Height)
+			}
+		}
+	}
+}
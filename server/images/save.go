@@ -0,0 +1,103 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Save processes an upload, stores every size and makes it the profile picture of the
+// user or dog, deleting the objects of the picture it replaces. It returns the full size URL.
+// A dog's picture is added to its gallery as the primary photo instead, see SaveDogPhoto.
+func Save(ctx context.Context, imgStore storage.ImageStore, db store.Store, entityType string, id graphql.ID, r io.Reader) (string, error) {
+	if entityType == "dogs" {
+		photo, err := SaveDogPhoto(ctx, imgStore, db, id, r, true)
+		return photo.URL, err
+	}
+	base, full, err := put(ctx, imgStore, entityType, id, r)
+	if err != nil {
+		return "", err
+	}
+	iid, _ := uuid.NewV1()
+	img := types.Image{
+		ID:          graphql.ID(iid.String()),
+		EntityType:  entityType,
+		EntityID:    id,
+		Key:         base,
+		Ext:         full.Ext,
+		ContentType: full.ContentType,
+		CreatedAt:   graphql.Time{Time: time.Now()},
+	}
+	// Other sizes are derived from the full size URL, see SizedURL
+	imgURL := imgStore.URL(Key(base, full))
+	old, err := db.ReplaceImage(img, imgURL)
+	if err != nil {
+		return "", err
+	}
+	Delete(ctx, imgStore, old)
+	return imgURL, nil
+}
+
+// SaveDogPhoto processes an upload, stores every size and appends it to the dog's gallery,
+// making it the profile picture when primary is set or the dog has none
+func SaveDogPhoto(ctx context.Context, imgStore storage.ImageStore, db store.Store, dogID graphql.ID, r io.Reader, primary bool) (types.DogPhoto, error) {
+	base, full, err := put(ctx, imgStore, "dogs", dogID, r)
+	if err != nil {
+		return types.DogPhoto{}, err
+	}
+	pid, _ := uuid.NewV1()
+	photo, err := db.InsertDogPhoto(types.DogPhoto{
+		ID:          graphql.ID(pid.String()),
+		Dog:         dogID,
+		Key:         base,
+		Ext:         full.Ext,
+		ContentType: full.ContentType,
+		URL:         imgStore.URL(Key(base, full)),
+		CreatedAt:   graphql.Time{Time: time.Now()},
+	}, primary)
+	if err == store.ErrTooManyPhotos {
+		// The key is not in the gallery, so nothing else refers to the objects just stored
+		Delete(ctx, imgStore, []types.Image{{Key: base, Ext: full.Ext}})
+	}
+	return photo, err
+}
+
+// put processes an upload and stores every size under its content addressed key
+func put(ctx context.Context, imgStore storage.ImageStore, entityType string, id graphql.ID, r io.Reader) (string, Variant, error) {
+	variants, err := Process(r)
+	if err != nil {
+		return "", Variant{}, err
+	}
+	full := variants[0]
+	base := ContentKey(entityType, string(id), full)
+	for _, v := range variants {
+		err := imgStore.Put(ctx, Key(base, v), bytes.NewReader(v.Data), int64(len(v.Data)), storage.PutOptions{
+			ContentType:        v.ContentType,
+			ContentDisposition: "inline",
+		})
+		if err != nil {
+			return "", Variant{}, err
+		}
+	}
+	return base, full, nil
+}
+
+// Delete removes every size of each image from storage. Failures are only logged since
+// the rows are already gone and a leftover object is harmless.
+func Delete(ctx context.Context, imgStore storage.ImageStore, imgs []types.Image) {
+	for _, img := range imgs {
+		for _, size := range Sizes {
+			if err := imgStore.Delete(ctx, SizeKey(img.Key, size, img.Ext)); err != nil {
+				log.Println("Delete image Error: ", err)
+			}
+		}
+	}
+}
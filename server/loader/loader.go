@@ -0,0 +1,238 @@
+// Package loader batches and caches the users, dogs and doggy dates fetched while
+// resolving a single graphql request, so each entity type costs at most one query
+// per round of resolvers.
+package loader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+type contextKey string
+
+const loadersKey contextKey = "loaders"
+
+// Loaders holds one dataloader per entity type, created fresh for every request
+type Loaders struct {
+	users        *dataloader.Loader
+	dogs         *dataloader.Loader
+	dates        *dataloader.Loader
+	places       *dataloader.Loader
+	photos       *dataloader.Loader // keyed by dog id
+	vaccinations *dataloader.Loader // keyed by dog id
+}
+
+// New creates the loaders for a request backed by db
+func New(db store.Store) *Loaders {
+	return &Loaders{
+		users: dataloader.NewBatchedLoader(batch("users", func(ids []graphql.ID) (map[graphql.ID]interface{}, error) {
+			m, err := db.GetUsersByIDs(ids)
+			res := map[graphql.ID]interface{}{}
+			for k, v := range m {
+				res[k] = v
+			}
+			return res, err
+		})),
+		dogs: dataloader.NewBatchedLoader(batch("dogs", func(ids []graphql.ID) (map[graphql.ID]interface{}, error) {
+			m, err := db.GetDogsByIDs(ids)
+			res := map[graphql.ID]interface{}{}
+			for k, v := range m {
+				res[k] = v
+			}
+			return res, err
+		})),
+		dates: dataloader.NewBatchedLoader(batch("doggy_dates", func(ids []graphql.ID) (map[graphql.ID]interface{}, error) {
+			m, err := db.GetDoggyDatesByIDs(ids)
+			res := map[graphql.ID]interface{}{}
+			for k, v := range m {
+				res[k] = v
+			}
+			return res, err
+		})),
+		places: dataloader.NewBatchedLoader(batch("places", func(ids []graphql.ID) (map[graphql.ID]interface{}, error) {
+			m, err := db.GetPlacesByIDs(ids)
+			res := map[graphql.ID]interface{}{}
+			for k, v := range m {
+				res[k] = v
+			}
+			return res, err
+		})),
+		photos: dataloader.NewBatchedLoader(batch("dog_photos", func(ids []graphql.ID) (map[graphql.ID]interface{}, error) {
+			m, err := db.GetDogPhotos(ids)
+			res := map[graphql.ID]interface{}{}
+			for _, id := range ids {
+				res[id] = m[id] // dogs without photos have an empty gallery
+			}
+			return res, err
+		})),
+		vaccinations: dataloader.NewBatchedLoader(batch("vaccinations", func(ids []graphql.ID) (map[graphql.ID]interface{}, error) {
+			m, err := db.GetVaccinations(ids)
+			res := map[graphql.ID]interface{}{}
+			for _, id := range ids {
+				res[id] = m[id] // dogs without records have none
+			}
+			return res, err
+		})),
+	}
+}
+
+// batch adapts a map returning fetch into a dataloader.BatchFunc, results must line up with keys
+func batch(tableType string, fetch func([]graphql.ID) (map[graphql.ID]interface{}, error)) dataloader.BatchFunc {
+	return func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+		ids := make([]graphql.ID, len(keys))
+		for i, k := range keys {
+			ids[i] = graphql.ID(k.String())
+		}
+		found, err := fetch(ids)
+		results := make([]*dataloader.Result, len(keys))
+		for i, id := range ids {
+			if err != nil {
+				results[i] = &dataloader.Result{Error: err}
+			} else if v, ok := found[id]; ok {
+				results[i] = &dataloader.Result{Data: v}
+			} else {
+				results[i] = &dataloader.Result{Error: &NotFoundError{tableType, id}}
+			}
+		}
+		return results
+	}
+}
+
+// NotFoundError is returned for an id the batch query found no row for
+type NotFoundError struct {
+	TableType string
+	ID        graphql.ID
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Error: %s %s not found", e.TableType, e.ID)
+}
+
+// Middleware attaches a fresh set of loaders to every request
+func Middleware(db store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := context.WithValue(req.Context(), loadersKey, New(db))
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// fromContext returns the loaders attached by Middleware
+func fromContext(ctx context.Context) (*Loaders, error) {
+	l, ok := ctx.Value(loadersKey).(*Loaders)
+	if !ok {
+		return nil, fmt.Errorf("Error: no loaders on request context")
+	}
+	return l, nil
+}
+
+// LoadUser returns the user with id, batched with other users loaded in the same request
+func LoadUser(ctx context.Context, id graphql.ID) (types.User, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return types.User{}, err
+	}
+	v, err := l.users.Load(ctx, dataloader.StringKey(id))()
+	if err != nil {
+		return types.User{}, err
+	}
+	return v.(types.User), nil
+}
+
+// LoadDog returns the dog with id, batched with other dogs loaded in the same request
+func LoadDog(ctx context.Context, id graphql.ID) (types.Dog, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return types.Dog{}, err
+	}
+	v, err := l.dogs.Load(ctx, dataloader.StringKey(id))()
+	if err != nil {
+		return types.Dog{}, err
+	}
+	return v.(types.Dog), nil
+}
+
+// LoadDogs returns the dogs with ids in order, skipping dogs that no longer exist
+func LoadDogs(ctx context.Context, ids []graphql.ID) ([]types.Dog, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(dataloader.Keys, len(ids))
+	for i, id := range ids {
+		keys[i] = dataloader.StringKey(id)
+	}
+	vs, errs := l.dogs.LoadMany(ctx, keys)()
+	var dogs []types.Dog
+	for i, v := range vs {
+		if errs != nil && errs[i] != nil {
+			if _, ok := errs[i].(*NotFoundError); ok {
+				continue
+			}
+			return nil, errs[i]
+		}
+		dogs = append(dogs, v.(types.Dog))
+	}
+	return dogs, nil
+}
+
+// LoadDate returns the doggy date with id, batched with other dates loaded in the same request
+func LoadDate(ctx context.Context, id graphql.ID) (types.Date, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return types.Date{}, err
+	}
+	v, err := l.dates.Load(ctx, dataloader.StringKey(id))()
+	if err != nil {
+		return types.Date{}, err
+	}
+	return v.(types.Date), nil
+}
+
+// LoadPlace returns the place with id, batched with other places loaded in the same request
+func LoadPlace(ctx context.Context, id graphql.ID) (types.Place, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return types.Place{}, err
+	}
+	v, err := l.places.Load(ctx, dataloader.StringKey(id))()
+	if err != nil {
+		return types.Place{}, err
+	}
+	return v.(types.Place), nil
+}
+
+// LoadDogPhotos returns the photos of the dog with id in position order, batched with the
+// galleries of other dogs loaded in the same request
+func LoadDogPhotos(ctx context.Context, dogID graphql.ID) ([]types.DogPhoto, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, err := l.photos.Load(ctx, dataloader.StringKey(dogID))()
+	if err != nil {
+		return nil, err
+	}
+	return v.([]types.DogPhoto), nil
+}
+
+// LoadVaccinations returns the vaccination records of the dog with id, batched with the
+// records of other dogs loaded in the same request
+func LoadVaccinations(ctx context.Context, dogID graphql.ID) ([]types.Vaccination, error) {
+	l, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, err := l.vaccinations.Load(ctx, dataloader.StringKey(dogID))()
+	if err != nil {
+		return nil, err
+	}
+	return v.([]types.Vaccination), nil
+}
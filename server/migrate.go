@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/raymondvooo/doggy-date-app/server/migrations"
+	"github.com/raymondvooo/doggy-date-app/server/postgres"
+)
+
+const migrateUsage = "usage: server migrate up|down|status"
+
+// migrate runs the `server migrate` subcommand against DATABASE_URL
+func migrate(args []string) {
+	if len(args) != 1 {
+		log.Fatalln(migrateUsage)
+	}
+	db, err := postgres.NewConnection(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		n, err := migrations.Up(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", n)
+	case "down":
+		m, err := migrations.Down(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if m == nil {
+			fmt.Println("No migrations to roll back")
+			return
+		}
+		fmt.Printf("Rolled back %04d_%s\n", m.Version, m.Name)
+	case "status":
+		st, err := migrations.Statuses(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range st {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		log.Fatalln(migrateUsage)
+	}
+}
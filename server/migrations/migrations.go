@@ -0,0 +1,233 @@
+// Package migrations applies the versioned SQL files embedded from sql/ and records
+// them in the schema_migrations table. Files are named <version>_<name>.up.sql and
+// <version>_<name>.down.sql.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the advisory lock held while migrating so two servers never migrate at once
+const lockID = 7243017
+
+// Migration is one version of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration along with when it was applied, AppliedAt is nil when pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the embedded migrations sorted by version
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migrations: unexpected file %s", name)
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		i := strings.IndexByte(base, '_')
+		if i < 0 {
+			return nil, fmt.Errorf("migrations: %s is not named <version>_<name>", name)
+		}
+		version, err := strconv.Atoi(base[:i])
+		if err != nil {
+			return nil, fmt.Errorf("migrations: %s has an invalid version: %v", name, err)
+		}
+		b, err := files.ReadFile(path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: base[i+1:]}
+			byVersion[version] = m
+		} else if m.Name != base[i+1:] {
+			return nil, fmt.Errorf("migrations: version %d is used by both %s and %s", version, m.Name, base[i+1:])
+		}
+		if direction == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+	var ms []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: version %d needs both an up and a down file", m.Version)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// ensureTable creates the schema_migrations tracking table
+func ensureTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version integer PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+// applied returns when each recorded version was applied
+func applied(db *sql.DB) (map[int]time.Time, error) {
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+// run executes one migration step and updates schema_migrations in the same transaction
+func run(db *sql.DB, m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op once committed
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+		return err
+	}
+	// Another server may have run this step while we waited for the lock
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", m.Version).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == up {
+		return tx.Commit()
+	}
+	body, record := m.Down, "DELETE FROM schema_migrations WHERE version = $1"
+	if up {
+		body, record = m.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+	}
+	if _, err := tx.Exec(body); err != nil {
+		return fmt.Errorf("migrations: %04d_%s: %v", m.Version, m.Name, err)
+	}
+	args := []interface{}{m.Version}
+	if up {
+		args = append(args, m.Name)
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up applies every pending migration in order and returns how many ran
+func Up(db *sql.DB) (int, error) {
+	ms, err := Load()
+	if err != nil {
+		return 0, err
+	}
+	if err := ensureTable(db); err != nil {
+		return 0, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, m := range ms {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		log.Printf("Migrating up: %04d_%s", m.Version, m.Name)
+		if err := run(db, m, true); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Down rolls back the most recently applied migration, returning nil when none are applied
+func Down(db *sql.DB) (*Migration, error) {
+	ms, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(ms) - 1; i >= 0; i-- {
+		m := ms[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		log.Printf("Migrating down: %04d_%s", m.Version, m.Name)
+		if err := run(db, m, false); err != nil {
+			return nil, err
+		}
+		return &m, nil
+	}
+	return nil, nil
+}
+
+// Statuses lists every migration and whether it has been applied
+func Statuses(db *sql.DB) ([]Status, error) {
+	ms, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var st []Status
+	for _, m := range ms {
+		s := Status{Migration: m}
+		if at, ok := done[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		st = append(st, s)
+	}
+	return st, nil
+}
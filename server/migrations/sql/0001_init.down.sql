@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS doggy_dates;
+DROP TABLE IF EXISTS dogs;
+DROP TABLE IF EXISTS users;
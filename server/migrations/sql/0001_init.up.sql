@@ -0,0 +1,29 @@
+-- Tables as they existed before migrations were tracked. IF NOT EXISTS lets an
+-- existing database adopt this baseline without changes.
+-- Column order matters: inserts in the postgres package are positional.
+CREATE TABLE IF NOT EXISTS users (
+  id uuid PRIMARY KEY,
+  name text NOT NULL,
+  email text NOT NULL UNIQUE,
+  dogs uuid[] NOT NULL DEFAULT '{}',
+  profile_image text NOT NULL DEFAULT '',
+  join_date timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS dogs (
+  id uuid PRIMARY KEY,
+  name text NOT NULL,
+  age integer NOT NULL,
+  breed text NOT NULL,
+  owner uuid NOT NULL REFERENCES users (id),
+  profile_image text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS doggy_dates (
+  id uuid PRIMARY KEY,
+  date timestamptz NOT NULL,
+  description text NOT NULL,
+  dogs uuid[] NOT NULL DEFAULT '{}',
+  location text NOT NULL,
+  "user" uuid NOT NULL REFERENCES users (id)
+);
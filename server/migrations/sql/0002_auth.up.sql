@@ -0,0 +1,13 @@
+-- Accounts created before passwords existed get an empty hash and cannot log in
+-- until a password is set.
+ALTER TABLE users ADD COLUMN password text NOT NULL DEFAULT '';
+
+-- token holds the sha256 digest of the session token, never the token itself
+CREATE TABLE sessions (
+  token text PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE doggy_dates
+  DROP COLUMN IF EXISTS cancel_reason,
+  DROP COLUMN IF EXISTS status;
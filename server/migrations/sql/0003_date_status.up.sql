@@ -0,0 +1,4 @@
+ALTER TABLE doggy_dates
+  ADD COLUMN status text NOT NULL DEFAULT 'PLANNED'
+    CHECK (status IN ('PLANNED', 'CONFIRMED', 'CANCELLED', 'COMPLETED')),
+  ADD COLUMN cancel_reason text NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+CREATE TABLE invitations (
+  id uuid PRIMARY KEY,
+  date_id uuid NOT NULL REFERENCES doggy_dates (id) ON DELETE CASCADE,
+  dog_id uuid NOT NULL REFERENCES dogs (id) ON DELETE CASCADE,
+  invited_by uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  status text NOT NULL DEFAULT 'PENDING'
+    CHECK (status IN ('PENDING', 'ACCEPTED', 'DECLINED', 'MAYBE')),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  responded_at timestamptz,
+  UNIQUE (date_id, dog_id)
+);
+
+CREATE INDEX invitations_dog_id_idx ON invitations (dog_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS dogs_owner_name_id_idx;
+DROP INDEX IF EXISTS doggy_dates_user_idx;
+DROP INDEX IF EXISTS doggy_dates_date_id_idx;
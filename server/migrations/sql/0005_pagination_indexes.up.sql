@@ -0,0 +1,4 @@
+-- Keyset pagination orders doggy dates by (date, id) and a user's dogs by (name, id)
+CREATE INDEX doggy_dates_date_id_idx ON doggy_dates (date, id);
+CREATE INDEX doggy_dates_user_idx ON doggy_dates ("user");
+CREATE INDEX dogs_owner_name_id_idx ON dogs (owner, name, id);
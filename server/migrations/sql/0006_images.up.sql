@@ -0,0 +1,15 @@
+-- Uploaded images, stored under content addressed keys. Exactly one of user_id and
+-- dog_id is set. Each size is stored at key.<size><ext>, e.g. key.thumbnail.jpg
+CREATE TABLE images (
+  id uuid PRIMARY KEY,
+  user_id uuid REFERENCES users (id) ON DELETE CASCADE,
+  dog_id uuid REFERENCES dogs (id) ON DELETE CASCADE,
+  key text NOT NULL,
+  ext text NOT NULL,
+  content_type text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  CHECK ((user_id IS NULL) <> (dog_id IS NULL))
+);
+
+CREATE INDEX images_user_id_idx ON images (user_id);
+CREATE INDEX images_dog_id_idx ON images (dog_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS doggy_dates_latitude_longitude_idx;
+ALTER TABLE doggy_dates
+  DROP CONSTRAINT IF EXISTS doggy_dates_coordinates_check,
+  DROP COLUMN IF EXISTS place_name,
+  DROP COLUMN IF EXISTS longitude,
+  DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,10 @@
+-- Map position of a doggy date, latitude and longitude are both set or both null
+ALTER TABLE doggy_dates
+  ADD COLUMN latitude double precision CHECK (latitude BETWEEN -90 AND 90),
+  ADD COLUMN longitude double precision CHECK (longitude BETWEEN -180 AND 180),
+  ADD COLUMN place_name text NOT NULL DEFAULT '',
+  ADD CONSTRAINT doggy_dates_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- nearbyDates narrows the search to a bounding box before computing distances
+CREATE INDEX doggy_dates_latitude_longitude_idx ON doggy_dates (latitude, longitude)
+  WHERE latitude IS NOT NULL;
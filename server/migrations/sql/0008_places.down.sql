@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+ALTER TABLE doggy_dates DROP COLUMN IF EXISTS place_id;
+DROP TABLE IF EXISTS places;
@@ -0,0 +1,18 @@
+-- Directory of dog parks and venues, managed by admins
+CREATE TABLE places (
+  id uuid PRIMARY KEY,
+  name text NOT NULL,
+  address text NOT NULL DEFAULT '',
+  latitude double precision NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+  longitude double precision NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+  amenities text[] NOT NULL DEFAULT '{}'
+    CHECK (amenities <@ ARRAY['OFF_LEASH', 'FENCED', 'WATER']::text[]),
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX places_latitude_longitude_idx ON places (latitude, longitude);
+
+ALTER TABLE doggy_dates ADD COLUMN place_id uuid REFERENCES places (id) ON DELETE SET NULL;
+
+-- Grant with UPDATE users SET is_admin = true WHERE email = '...'
+ALTER TABLE users ADD COLUMN is_admin boolean NOT NULL DEFAULT false;
@@ -0,0 +1,10 @@
+ALTER TABLE dogs
+  DROP COLUMN IF EXISTS good_with_people,
+  DROP COLUMN IF EXISTS good_with_puppies,
+  DROP COLUMN IF EXISTS good_with_small_dogs,
+  DROP COLUMN IF EXISTS play_styles,
+  DROP COLUMN IF EXISTS energy_level,
+  DROP COLUMN IF EXISTS neutered,
+  DROP COLUMN IF EXISTS sex,
+  DROP COLUMN IF EXISTS weight_kg,
+  DROP COLUMN IF EXISTS size;
@@ -0,0 +1,12 @@
+-- Profile details used to decide which dogs should meet, NULL when the owner has not said
+ALTER TABLE dogs
+  ADD COLUMN size text CHECK (size IN ('TOY', 'SMALL', 'MEDIUM', 'LARGE', 'GIANT')),
+  ADD COLUMN weight_kg double precision CHECK (weight_kg > 0),
+  ADD COLUMN sex text CHECK (sex IN ('MALE', 'FEMALE')),
+  ADD COLUMN neutered boolean,
+  ADD COLUMN energy_level text CHECK (energy_level IN ('LOW', 'MEDIUM', 'HIGH')),
+  ADD COLUMN play_styles text[] NOT NULL DEFAULT '{}'
+    CHECK (play_styles <@ ARRAY['CHASE', 'WRESTLE', 'FETCH', 'TUG', 'SNIFF', 'GENTLE']::text[]),
+  ADD COLUMN good_with_small_dogs boolean,
+  ADD COLUMN good_with_puppies boolean,
+  ADD COLUMN good_with_people boolean;
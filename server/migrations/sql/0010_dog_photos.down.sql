@@ -0,0 +1,5 @@
+-- Only the primary photo survives as the dog's picture
+INSERT INTO images (id, dog_id, key, ext, content_type, created_at)
+SELECT id, dog_id, key, ext, content_type, created_at FROM dog_photos WHERE is_primary;
+
+DROP TABLE IF EXISTS dog_photos;
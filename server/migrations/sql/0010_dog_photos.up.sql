@@ -0,0 +1,26 @@
+-- Photo galleries for dogs. Each photo is stored like images, every size at
+-- key.<size><ext>, and url is the full size URL. dogs.profile_image holds the url of
+-- the primary photo so existing reads keep working.
+CREATE TABLE dog_photos (
+  id uuid PRIMARY KEY,
+  dog_id uuid NOT NULL REFERENCES dogs (id) ON DELETE CASCADE,
+  key text NOT NULL,
+  ext text NOT NULL,
+  content_type text NOT NULL,
+  url text NOT NULL,
+  position integer NOT NULL,
+  is_primary boolean NOT NULL DEFAULT false,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (dog_id, key),
+  -- deferred so reordering can swap positions within a transaction
+  UNIQUE (dog_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE UNIQUE INDEX dog_photos_primary_idx ON dog_photos (dog_id) WHERE is_primary;
+
+-- Current dog pictures become the first photo of each gallery
+INSERT INTO dog_photos (id, dog_id, key, ext, content_type, url, position, is_primary, created_at)
+SELECT i.id, i.dog_id, i.key, i.ext, i.content_type, d.profile_image, 0, true, i.created_at
+FROM images i INNER JOIN dogs d ON d.id = i.dog_id;
+
+DELETE FROM images WHERE dog_id IS NOT NULL;
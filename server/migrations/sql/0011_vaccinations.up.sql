@@ -0,0 +1,15 @@
+-- Vaccine records of dogs. certificate_key is the key of an uploaded certificate in the
+-- private document store, empty when there is none. A NULL expires_on never expires.
+CREATE TABLE vaccinations (
+  id uuid PRIMARY KEY,
+  dog_id uuid NOT NULL REFERENCES dogs (id) ON DELETE CASCADE,
+  vaccine text NOT NULL
+    CHECK (vaccine IN ('RABIES', 'DHPP', 'BORDETELLA', 'LEPTOSPIROSIS', 'CANINE_INFLUENZA', 'LYME')),
+  administered_on date NOT NULL,
+  expires_on date CHECK (expires_on > administered_on),
+  certificate_key text NOT NULL DEFAULT '',
+  certificate_content_type text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX vaccinations_dog_id_idx ON vaccinations (dog_id);
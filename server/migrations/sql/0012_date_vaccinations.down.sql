@@ -0,0 +1 @@
+ALTER TABLE doggy_dates DROP COLUMN IF EXISTS requires_vaccinations;
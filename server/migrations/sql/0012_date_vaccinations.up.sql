@@ -0,0 +1,3 @@
+-- Vaccines every dog on a date must have current on the day of the date
+ALTER TABLE doggy_dates ADD COLUMN requires_vaccinations text[] NOT NULL DEFAULT '{}'
+  CHECK (requires_vaccinations <@ ARRAY['RABIES', 'DHPP', 'BORDETELLA', 'LEPTOSPIROSIS', 'CANINE_INFLUENZA', 'LYME']::text[]);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_latitude_longitude_idx;
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS users_coordinates_check,
+  DROP COLUMN IF EXISTS longitude,
+  DROP COLUMN IF EXISTS latitude;
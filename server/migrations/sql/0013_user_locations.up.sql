@@ -0,0 +1,10 @@
+-- Where a household is, used to suggest playmates nearby. Latitude and longitude are both
+-- set or both null.
+ALTER TABLE users
+  ADD COLUMN latitude double precision CHECK (latitude BETWEEN -90 AND 90),
+  ADD COLUMN longitude double precision CHECK (longitude BETWEEN -180 AND 180),
+  ADD CONSTRAINT users_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- suggestedPlaymates narrows the search to a bounding box before computing distances
+CREATE INDEX users_latitude_longitude_idx ON users (latitude, longitude)
+  WHERE latitude IS NOT NULL;
@@ -0,0 +1,13 @@
+-- A dog's like or pass of another dog, liked is false for a pass. A dog decides once
+-- per dog, deciding again replaces the row. Two dogs liking each other are a match.
+CREATE TABLE likes (
+  from_dog uuid NOT NULL REFERENCES dogs (id) ON DELETE CASCADE,
+  to_dog uuid NOT NULL REFERENCES dogs (id) ON DELETE CASCADE,
+  liked boolean NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (from_dog, to_dog),
+  CHECK (from_dog <> to_dog)
+);
+
+-- the primary key finds a dog's own likes, this finds the likes it received
+CREATE INDEX likes_to_dog_idx ON likes (to_dog);
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"github.com/graph-gophers/graphql-go"
+	"github.com/lib/pq"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"time"
+)
+
+// GetUsersByIDs is called by the user dataloader to fetch a batch of users without their dogs
+func (d *Db) GetUsersByIDs(userIds []graphql.ID) (map[graphql.ID]types.User, error) {
+	log.Println("Starting: GetUsersByIDs Query")
+	var uus []uuid.UUID
+	GraphqlIDToUUID(userIds, &uus)
+	rows, err := d.Query(`SELECT
+	u.id,
+	u.name,
+	u.dogs,
+	u.profile_image,
+	u.join_date,
+	u.latitude,
+	u.longitude
+	FROM users u
+	WHERE u.id = ANY($1);`, pq.Array(uus))
+	if err != nil {
+		log.Println("GetUsersByIDs Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	uMap := map[graphql.ID]types.User{}
+	for rows.Next() {
+		var u types.User
+		var joinDate time.Time
+		var userDogs []string
+		var lat, lng *float64
+		err = rows.Scan(
+			&u.ID,
+			&u.Name,
+			pq.Array(&userDogs), // readable [] string type
+			&u.ProfileImageURL,
+			&joinDate, // readable Time type
+			&lat,
+			&lng,
+		)
+		if err != nil {
+			log.Println("GetUsersByIDs error scanning rows: ", err)
+			return uMap, err
+		}
+		u.JoinDate = graphql.Time{Time: joinDate} // convert Time to graphql.Time
+		u.Home = coordinates(lat, lng)
+		StringToGraphqlID(userDogs, &u.Dogs)
+		uMap[u.ID] = u
+	}
+	log.Println("Success: GetUsersByIDs Query")
+	return uMap, rows.Err()
+}
+
+// GetDogsByIDs is called by the dog dataloader to fetch a batch of dogs without their owners
+func (d *Db) GetDogsByIDs(dogIds []graphql.ID) (map[graphql.ID]types.Dog, error) {
+	log.Println("Starting: GetDogsByIDs Query")
+	var dus []uuid.UUID
+	GraphqlIDToUUID(dogIds, &dus)
+	rows, err := d.Query(`SELECT
+	d.id,
+	d.name,
+	d.age,
+	d.breed,
+	d.owner,
+	d.profile_image,
+	`+dogProfileColumns+`
+	FROM dogs d
+	WHERE d.id = ANY($1);`, pq.Array(dus))
+	if err != nil {
+		log.Println("GetDogsByIDs Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	dogMap := map[graphql.ID]types.Dog{}
+	for rows.Next() {
+		var dog types.Dog
+		err = rows.Scan(append([]interface{}{
+			&dog.ID,
+			&dog.Name,
+			&dog.Age,
+			&dog.Breed,
+			&dog.Owner,
+			&dog.ProfileImageURL,
+		}, dogProfileDest(&dog.DogProfile)...)...)
+		if err != nil {
+			log.Println("GetDogsByIDs error scanning rows: ", err)
+			return dogMap, err
+		}
+		dogMap[dog.ID] = dog
+	}
+	log.Println("Success: GetDogsByIDs Query")
+	return dogMap, rows.Err()
+}
+
+// GetDoggyDatesByIDs is called by the doggy date dataloader to fetch a batch of doggy dates
+func (d *Db) GetDoggyDatesByIDs(dateIds []graphql.ID) (map[graphql.ID]types.Date, error) {
+	log.Println("Starting: GetDoggyDatesByIDs Query")
+	var dus []uuid.UUID
+	GraphqlIDToUUID(dateIds, &dus)
+	rows, err := d.Query(`SELECT `+dateColumns+`
+	FROM doggy_dates WHERE id = ANY($1);`, pq.Array(dus))
+	if err != nil {
+		log.Println("GetDoggyDatesByIDs Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	dates := map[graphql.ID]types.Date{}
+	for rows.Next() {
+		date, err := scanDoggyDate(rows)
+		if err != nil {
+			log.Println("GetDoggyDatesByIDs error scanning rows: ", err)
+			return dates, err
+		}
+		dates[date.ID] = date
+	}
+	log.Println("Success: GetDoggyDatesByIDs Query")
+	return dates, rows.Err()
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// likeEscaper escapes LIKE wildcards so user input only matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// dateWhere translates the filter into a parameterized condition on doggy_dates dd,
+// placeholders start at $n
+func dateWhere(f store.DateFilter, n int) (string, []interface{}) {
+	conds := []string{"TRUE"}
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, fmt.Sprintf(cond, n))
+		args = append(args, arg)
+		n++
+	}
+	if f.From != nil {
+		add("dd.date >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("dd.date < $%d", *f.To)
+	}
+	if f.Location != "" {
+		add("dd.location ILIKE '%%' || $%d || '%%'", likeEscaper.Replace(f.Location))
+	}
+	if f.Organizer != "" {
+		add("dd.user = $%d::uuid", string(f.Organizer))
+	}
+	if f.Dog != "" {
+		add("$%d::uuid = ANY(dd.dogs)", string(f.Dog))
+	}
+	if f.Breed != "" {
+		add(`EXISTS (SELECT 1 FROM dogs bd WHERE bd.id = ANY(dd.dogs)
+		AND bd.breed ILIKE '%%' || $%d || '%%')`, likeEscaper.Replace(f.Breed))
+	}
+	if len(f.Statuses) > 0 {
+		add("dd.status = ANY($%d)", pq.Array(f.Statuses))
+	} else if !f.IncludeCancelled {
+		add("dd.status <> $%d", types.DateStatusCancelled)
+	}
+	return strings.Join(conds, " AND "), args
+}
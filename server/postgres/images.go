@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"time"
+)
+
+// imageOwnerColumn maps an entity type to the images column referencing it
+var imageOwnerColumn = map[string]string{
+	"users": "user_id",
+	"dogs":  "dog_id",
+}
+
+// selectImages reads the key and ext of the rows of table matching where, so their objects
+// can still be deleted once the rows are gone
+func selectImages(tx *sql.Tx, table string, where string, id uuid.UUID) ([]types.Image, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT key, ext FROM %s WHERE %s", table, where), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var imgs []types.Image
+	for rows.Next() {
+		var img types.Image
+		if err := rows.Scan(&img.Key, &img.Ext); err != nil {
+			return nil, err
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs, rows.Err()
+}
+
+// ReplaceImage queries database to swap the image of a user or dog in one transaction
+func (d *Db) ReplaceImage(img types.Image, imgURL string) ([]types.Image, error) {
+	col, ok := imageOwnerColumn[img.EntityType]
+	if !ok {
+		return nil, fmt.Errorf("Error: images cannot belong to %s", img.EntityType)
+	}
+	log.Println("Starting: ReplaceImage Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("ReplaceImage Begin Error: ", err)
+		return nil, err
+	}
+	defer tx.Rollback() // no-op once committed
+
+	eid, _ := uuid.FromString(string(img.EntityID))
+	// Lock the entity so concurrent uploads replace each other in order
+	q := fmt.Sprintf("UPDATE %s SET profile_image=$1 WHERE id=$2", img.EntityType)
+	res, err := tx.Exec(q, imgURL, eid)
+	if err != nil {
+		log.Println("ReplaceImage Execution Error: ", err)
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	// Re-uploading the same picture keeps its row and objects
+	q = fmt.Sprintf(`DELETE FROM images WHERE %s=$1 AND key<>$2
+	RETURNING id, key, ext, content_type, created_at`, col)
+	rows, err := tx.Query(q, eid, img.Key)
+	if err != nil {
+		log.Println("ReplaceImage Execution Error: ", err)
+		return nil, err
+	}
+	var old []types.Image
+	for rows.Next() {
+		prev := types.Image{EntityType: img.EntityType, EntityID: img.EntityID}
+		var createdAt time.Time
+		if err := rows.Scan(&prev.ID, &prev.Key, &prev.Ext, &prev.ContentType, &createdAt); err != nil {
+			rows.Close()
+			log.Println("ReplaceImage error scanning rows: ", err)
+			return nil, err
+		}
+		prev.CreatedAt = graphql.Time{Time: createdAt}
+		old = append(old, prev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	q = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM images WHERE %s=$1 AND key=$2)", col)
+	if err := tx.QueryRow(q, eid, img.Key).Scan(&exists); err != nil {
+		log.Println("ReplaceImage Query Error: ", err)
+		return nil, err
+	}
+	if !exists {
+		iid, _ := uuid.FromString(string(img.ID))
+		q = fmt.Sprintf("INSERT INTO images (id, %s, key, ext, content_type, created_at) VALUES ($1, $2, $3, $4, $5, $6)", col)
+		if _, err := tx.Exec(q, iid, eid, img.Key, img.Ext, img.ContentType, img.CreatedAt.Time); err != nil {
+			log.Println("ReplaceImage Execution Error: ", err)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("ReplaceImage Commit Error: ", err)
+		return nil, err
+	}
+	log.Println("Success: ReplaceImage Execution")
+	return old, nil
+}
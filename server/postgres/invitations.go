@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"time"
+)
+
+// invitationColumns lists the invitations columns read by scanInvitation
+const invitationColumns = `i.id, i.date_id, i.dog_id, i.invited_by, i.status, i.created_at, i.responded_at`
+
+// scanInvitation copies an invitations row into an Invitation
+func scanInvitation(row scanner) (types.Invitation, error) {
+	var inv types.Invitation
+	var createdAt time.Time
+	var respondedAt *time.Time
+	err := row.Scan(
+		&inv.ID,
+		&inv.Date,
+		&inv.Dog,
+		&inv.InvitedBy,
+		&inv.Status,
+		&createdAt, // readable Time type
+		&respondedAt,
+	)
+	if err != nil {
+		return types.Invitation{}, err
+	}
+	inv.CreatedAt = graphql.Time{Time: createdAt} // convert Time to graphql.Time
+	if respondedAt != nil {
+		inv.RespondedAt = &graphql.Time{Time: *respondedAt}
+	}
+	return inv, nil
+}
+
+// queryInvitations runs an invitations query and collects every row
+func (d *Db) queryInvitations(name string, q string, args ...interface{}) ([]types.Invitation, error) {
+	log.Printf("Starting: %s Query", name)
+	rows, err := d.Query(q, args...)
+	if err != nil {
+		log.Printf("%s Query Error: %v", name, err)
+		return nil, err
+	}
+	defer rows.Close()
+	var invs []types.Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			log.Printf("%s error scanning rows: %v", name, err)
+			return invs, err
+		}
+		invs = append(invs, inv)
+	}
+	log.Printf("Success: %s Query", name)
+	return invs, rows.Err()
+}
+
+// InsertInvitation queries database to invite a dog to a doggy date
+func (d *Db) InsertInvitation(dateID graphql.ID, dogID graphql.ID, invitedBy graphql.ID) (types.Invitation, error) {
+	log.Println("Starting: InsertInvitation Execution")
+	inv, err := insertInvitation(d, dateID, dogID, invitedBy)
+	if err != nil {
+		log.Println("InsertInvitation Execution Error: ", err)
+		return types.Invitation{}, err
+	}
+	log.Println("Success: InsertInvitation Execution")
+	return inv, nil
+}
+
+// insertInvitation inserts a pending invitation with ex, which may be a transaction
+func insertInvitation(ex execer, dateID graphql.ID, dogID graphql.ID, invitedBy graphql.ID) (types.Invitation, error) {
+	iid, _ := uuid.NewV1()
+	ddid, _ := uuid.FromString(string(dateID))
+	did, _ := uuid.FromString(string(dogID))
+	uid, _ := uuid.FromString(string(invitedBy))
+	createdAt := time.Now()
+	if _, err := ex.Exec("INSERT INTO invitations VALUES ($1, $2, $3, $4, $5, $6, NULL)",
+		iid, ddid, did, uid, types.InvitationPending, createdAt); err != nil {
+		return types.Invitation{}, err
+	}
+	return types.Invitation{
+		ID:        graphql.ID(iid.String()),
+		Date:      dateID,
+		Dog:       dogID,
+		InvitedBy: invitedBy,
+		Status:    types.InvitationPending,
+		CreatedAt: graphql.Time{Time: createdAt}}, nil
+}
+
+// GetInvitationByID queries database for a single invitation
+func (d *Db) GetInvitationByID(id graphql.ID) (types.Invitation, error) {
+	log.Println("Starting: GetInvitationByID Query")
+	iid, _ := uuid.FromString(string(id))
+	inv, err := scanInvitation(d.QueryRow(`SELECT `+invitationColumns+` FROM invitations i WHERE i.id = $1`, iid))
+	if err != nil {
+		log.Println("GetInvitationByID Query Error: ", err)
+		return types.Invitation{}, err
+	}
+	log.Println("Success: GetInvitationByID Query")
+	return inv, nil
+}
+
+// GetInvitationsByDate queries database for every invitation sent for a doggy date
+func (d *Db) GetInvitationsByDate(dateID graphql.ID) ([]types.Invitation, error) {
+	ddid, _ := uuid.FromString(string(dateID))
+	return d.queryInvitations("GetInvitationsByDate", `SELECT `+invitationColumns+`
+	FROM invitations i
+	WHERE i.date_id = $1
+	ORDER BY i.created_at;`, ddid)
+}
+
+// GetPendingInvitationsByOwner queries database for unanswered invitations sent to a user's dogs
+func (d *Db) GetPendingInvitationsByOwner(owner graphql.ID) ([]types.Invitation, error) {
+	uid, _ := uuid.FromString(string(owner))
+	return d.queryInvitations("GetPendingInvitationsByOwner", `SELECT `+invitationColumns+`
+	FROM invitations i INNER JOIN dogs d ON i.dog_id = d.id
+	WHERE d.owner = $1 AND i.status IN ($2, $3)
+	ORDER BY i.created_at;`, uid, types.InvitationPending, types.InvitationMaybe)
+}
+
+// RespondToInvitation queries database to record an RSVP. The invited dog joins the
+// doggy date's dogs when accepted and is taken off again on any other answer.
+func (d *Db) RespondToInvitation(id graphql.ID, status string) (types.Invitation, error) {
+	log.Println("Starting: RespondToInvitation Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("RespondToInvitation Begin Error: ", err)
+		return types.Invitation{}, err
+	}
+	defer tx.Rollback() // no-op once committed
+	iid, _ := uuid.FromString(string(id))
+	inv, err := scanInvitation(tx.QueryRow(`UPDATE invitations i SET status = $1, responded_at = $2
+	WHERE i.id = $3 RETURNING `+invitationColumns, status, time.Now(), iid))
+	if err != nil {
+		log.Println("RespondToInvitation Execution Error: ", err)
+		return types.Invitation{}, err
+	}
+	if status == types.InvitationAccepted {
+		_, err = tx.Exec(`UPDATE doggy_dates SET dogs = array_append(dogs, $1)
+		WHERE id = $2 AND NOT ($1 = ANY(dogs))`, string(inv.Dog), string(inv.Date))
+	} else {
+		_, err = tx.Exec(`UPDATE doggy_dates SET dogs = array_remove(dogs, $1) WHERE id = $2`, string(inv.Dog), string(inv.Date))
+	}
+	if err != nil {
+		log.Println("RespondToInvitation Execution Error: ", err)
+		return types.Invitation{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("RespondToInvitation Commit Error: ", err)
+		return types.Invitation{}, err
+	}
+	log.Println("Success: RespondToInvitation Execution")
+	return inv, nil
+}
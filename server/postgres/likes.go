@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"log"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// InsertLike queries database to record a like or pass, replacing the dog's earlier decision
+// about the same dog. Repeating the same decision keeps its original time.
+func (d *Db) InsertLike(like types.Like) (types.Like, error) {
+	log.Println("Starting: InsertLike Execution")
+	from, _ := uuid.FromString(string(like.From))
+	to, _ := uuid.FromString(string(like.To))
+	var l types.Like
+	var createdAt time.Time
+	err := d.QueryRow(`INSERT INTO likes (from_dog, to_dog, liked) VALUES ($1, $2, $3)
+	ON CONFLICT (from_dog, to_dog) DO UPDATE SET
+	liked = EXCLUDED.liked,
+	created_at = CASE WHEN likes.liked = EXCLUDED.liked THEN likes.created_at ELSE now() END
+	RETURNING from_dog, to_dog, liked, created_at;`, from, to, like.Liked).Scan(
+		&l.From,
+		&l.To,
+		&l.Liked,
+		&createdAt, // readable Time type
+	)
+	if err != nil {
+		log.Println("InsertLike Execution Error: ", err)
+		return types.Like{}, err
+	}
+	l.CreatedAt = graphql.Time{Time: createdAt} // convert Time to graphql.Time
+	log.Println("Success: InsertLike Execution")
+	return l, nil
+}
+
+// GetMatches queries database for the dogs that liked the dog back, most recently matched first
+func (d *Db) GetMatches(dogID graphql.ID) ([]types.Match, error) {
+	log.Println("Starting: GetMatches Query")
+	did, _ := uuid.FromString(string(dogID))
+	rows, err := d.Query(`SELECT theirs.from_dog, GREATEST(mine.created_at, theirs.created_at) AS matched_at
+	FROM likes mine
+	INNER JOIN likes theirs ON theirs.from_dog = mine.to_dog AND theirs.to_dog = mine.from_dog
+	WHERE mine.from_dog = $1 AND mine.liked AND theirs.liked
+	ORDER BY matched_at DESC, theirs.from_dog;`, did)
+	if err != nil {
+		log.Println("GetMatches Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var matches []types.Match
+	for rows.Next() {
+		var m types.Match
+		var matchedAt time.Time
+		if err := rows.Scan(&m.Dog, &matchedAt); err != nil {
+			log.Println("GetMatches error scanning rows: ", err)
+			return matches, err
+		}
+		m.MatchedAt = graphql.Time{Time: matchedAt} // convert Time to graphql.Time
+		matches = append(matches, m)
+	}
+	log.Println("Success: GetMatches Query")
+	return matches, rows.Err()
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// haversineSQL returns the great-circle distance in km from the point in placeholders latArg
+// and lngArg to the latitude and longitude columns of the table aliased alias, the same formula
+// as geo.DistanceKm
+func haversineSQL(alias string, latArg int, lngArg int) string {
+	return fmt.Sprintf(`2 * %[4]v * asin(least(1, sqrt(
+	power(sin(radians(%[1]s.latitude - $%[2]d) / 2), 2) +
+	cos(radians($%[2]d)) * cos(radians(%[1]s.latitude)) * power(sin(radians(%[1]s.longitude - $%[3]d) / 2), 2)
+)))`, alias, latArg, lngArg, geo.EarthRadiusKm)
+}
+
+// NearbyDates queries database for the doggy dates matching filter within radiusKm of
+// center, closest first
+func (d *Db) NearbyDates(center types.Coordinates, radiusKm float64, filter store.DateFilter, limit int) ([]store.NearbyDate, error) {
+	log.Println("Starting: NearbyDates Query")
+	where, args := dateWhere(filter, 1)
+	n := len(args) + 1
+	distance := haversineSQL("dd", n, n+1)
+	box := geo.BoundingBox(center, radiusKm)
+	args = append(args, center.Latitude, center.Longitude, box.MinLat, box.MaxLat, box.MinLng, box.MaxLng, radiusKm, limit)
+	q := fmt.Sprintf(`SELECT * FROM (
+	SELECT %s, %s AS distance_km
+	FROM doggy_dates dd
+	WHERE %s
+	AND dd.latitude BETWEEN $%d AND $%d
+	AND dd.longitude BETWEEN $%d AND $%d
+	) nearby
+	WHERE distance_km <= $%d
+	ORDER BY distance_km, id
+	LIMIT $%d`, dateColumns, distance, where, n+2, n+3, n+4, n+5, n+6, n+7)
+	rows, err := d.Query(q, args...)
+	if err != nil {
+		log.Println("NearbyDates Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var dates []store.NearbyDate
+	for rows.Next() {
+		var nd store.NearbyDate
+		date, err := scanDoggyDate(scannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &nd.DistanceKm)...)
+		}))
+		if err != nil {
+			log.Println("NearbyDates error scanning rows: ", err)
+			return dates, err
+		}
+		nd.Date = date
+		dates = append(dates, nd)
+	}
+	log.Println("Success: NearbyDates Query")
+	return dates, rows.Err()
+}
+
+// scannerFunc adapts a function to the scanner interface, used to scan extra columns
+// after the ones a scan helper reads
+type scannerFunc func(dest ...interface{}) error
+
+// Scan calls f
+func (f scannerFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/raymondvooo/doggy-date-app/server/store"
+)
+
+// keyset builds the WHERE condition, ORDER BY and LIMIT clauses for page. keyExpr and idExpr are
+// the sort columns, keyType is the postgres type the cursor key is cast to, n is the next
+// placeholder number. One extra row is fetched so callers can tell if there are more.
+func keyset(page store.Page, keyExpr string, idExpr string, keyType string, n int) (string, string, []interface{}) {
+	op, dir := ">", "ASC"
+	if page.Backward != page.Descending {
+		op, dir = "<", "DESC"
+	}
+	cond := "TRUE"
+	var args []interface{}
+	if page.Cursor != nil {
+		cond = fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d::uuid)", keyExpr, idExpr, op, n, keyType, n+1)
+		args = append(args, page.Cursor.Key, page.Cursor.ID)
+	}
+	tail := fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT %d", keyExpr, dir, idExpr, dir, page.Limit+1)
+	return cond, tail, args
+}
+
+// trimPage drops the extra row fetched by keyset and restores ascending order for
+// backward pages, reporting whether more rows exist past the page
+func trimPage(n int, page store.Page, swap func(i, j int)) (int, bool) {
+	more := n > page.Limit
+	if more {
+		n = page.Limit
+	}
+	if page.Backward {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	return n, more
+}
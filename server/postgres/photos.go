@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/lib/pq"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"time"
+)
+
+// photoColumns lists the dog_photos columns read by scanDogPhoto
+const photoColumns = `id, dog_id, key, ext, content_type, url, position, is_primary, created_at`
+
+// scanDogPhoto reads a dog_photos row selected with photoColumns
+func scanDogPhoto(row scanner) (types.DogPhoto, error) {
+	var p types.DogPhoto
+	var createdAt time.Time
+	err := row.Scan(&p.ID, &p.Dog, &p.Key, &p.Ext, &p.ContentType, &p.URL, &p.Position, &p.Primary, &createdAt)
+	p.CreatedAt = graphql.Time{Time: createdAt}
+	return p, err
+}
+
+// GetDogPhotos is called by the dog photos dataloader, each dog's photos are in position order
+func (d *Db) GetDogPhotos(dogIds []graphql.ID) (map[graphql.ID][]types.DogPhoto, error) {
+	log.Println("Starting: GetDogPhotos Query")
+	var dus []uuid.UUID
+	GraphqlIDToUUID(dogIds, &dus)
+	rows, err := d.Query(`SELECT `+photoColumns+`
+	FROM dog_photos
+	WHERE dog_id = ANY($1)
+	ORDER BY dog_id, position;`, pq.Array(dus))
+	if err != nil {
+		log.Println("GetDogPhotos Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	photos := map[graphql.ID][]types.DogPhoto{}
+	for rows.Next() {
+		p, err := scanDogPhoto(rows)
+		if err != nil {
+			log.Println("GetDogPhotos error scanning rows: ", err)
+			return photos, err
+		}
+		photos[p.Dog] = append(photos[p.Dog], p)
+	}
+	log.Println("Success: GetDogPhotos Query")
+	return photos, rows.Err()
+}
+
+// InsertDogPhoto queries database to append a photo to a dog's gallery in one transaction
+func (d *Db) InsertDogPhoto(photo types.DogPhoto, primary bool) (types.DogPhoto, error) {
+	log.Println("Starting: InsertDogPhoto Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("InsertDogPhoto Begin Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	defer tx.Rollback() // no-op once committed
+
+	did, _ := uuid.FromString(string(photo.Dog))
+	// Lock the dog so concurrent uploads get distinct positions
+	if err := tx.QueryRow("SELECT id FROM dogs WHERE id=$1 FOR UPDATE", did).Scan(&did); err != nil {
+		log.Println("InsertDogPhoto Query Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	// Re-uploading the same picture keeps its row and objects
+	p, err := scanDogPhoto(tx.QueryRow(`SELECT `+photoColumns+` FROM dog_photos WHERE dog_id=$1 AND key=$2`, did, photo.Key))
+	if err == sql.ErrNoRows {
+		// Counted under the dog's lock so concurrent uploads cannot overfill the gallery
+		var count int
+		var hasPrimary bool
+		if err := tx.QueryRow("SELECT count(*), COALESCE(bool_or(is_primary), false) FROM dog_photos WHERE dog_id=$1", did).Scan(&count, &hasPrimary); err != nil {
+			log.Println("InsertDogPhoto Query Error: ", err)
+			return types.DogPhoto{}, err
+		}
+		if count >= store.MaxDogPhotos {
+			return types.DogPhoto{}, store.ErrTooManyPhotos
+		}
+		primary = primary || !hasPrimary
+		pid, _ := uuid.FromString(string(photo.ID))
+		p, err = scanDogPhoto(tx.QueryRow(`INSERT INTO dog_photos
+		SELECT $1, $2, $3, $4, $5, $6, COALESCE(MAX(position) + 1, 0), false, $7
+		FROM dog_photos WHERE dog_id = $2
+		RETURNING `+photoColumns, pid, did, photo.Key, photo.Ext, photo.ContentType, photo.URL, photo.CreatedAt.Time))
+	}
+	if err != nil {
+		log.Println("InsertDogPhoto Query Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	if primary && !p.Primary {
+		if err := setPrimaryPhoto(tx, did, p.ID); err != nil {
+			log.Println("InsertDogPhoto Execution Error: ", err)
+			return types.DogPhoto{}, err
+		}
+		p.Primary = true
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("InsertDogPhoto Commit Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	log.Println("Success: InsertDogPhoto Execution")
+	return p, nil
+}
+
+// setPrimaryPhoto moves the primary flag to photo id, or to the first photo when id is empty,
+// and copies its url to the dog's profile image
+func setPrimaryPhoto(tx *sql.Tx, did uuid.UUID, id graphql.ID) error {
+	if id == "" {
+		err := tx.QueryRow("SELECT id FROM dog_photos WHERE dog_id=$1 ORDER BY position LIMIT 1", did).Scan(&id)
+		if err == sql.ErrNoRows {
+			_, err = tx.Exec("UPDATE dogs SET profile_image='' WHERE id=$1", did)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+	}
+	pid, _ := uuid.FromString(string(id))
+	// Clear first, the partial unique index allows one primary photo per dog
+	if _, err := tx.Exec("UPDATE dog_photos SET is_primary=false WHERE dog_id=$1 AND is_primary AND id<>$2", did, pid); err != nil {
+		return err
+	}
+	var url string
+	err := tx.QueryRow("UPDATE dog_photos SET is_primary=true WHERE dog_id=$1 AND id=$2 RETURNING url", did, pid).Scan(&url)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE dogs SET profile_image=$1 WHERE id=$2", url, did)
+	return err
+}
+
+// DeleteDogPhoto queries database to remove a photo from a dog's gallery in one transaction
+func (d *Db) DeleteDogPhoto(dogID graphql.ID, id graphql.ID) (types.DogPhoto, error) {
+	log.Println("Starting: DeleteDogPhoto Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("DeleteDogPhoto Begin Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	defer tx.Rollback() // no-op once committed
+
+	did, _ := uuid.FromString(string(dogID))
+	pid, _ := uuid.FromString(string(id))
+	p, err := scanDogPhoto(tx.QueryRow(`DELETE FROM dog_photos WHERE dog_id=$1 AND id=$2 RETURNING `+photoColumns, did, pid))
+	if err != nil {
+		log.Println("DeleteDogPhoto Execution Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	if _, err := tx.Exec("UPDATE dog_photos SET position=position-1 WHERE dog_id=$1 AND position>$2", did, p.Position); err != nil {
+		log.Println("DeleteDogPhoto Execution Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	if p.Primary {
+		if err := setPrimaryPhoto(tx, did, ""); err != nil {
+			log.Println("DeleteDogPhoto Execution Error: ", err)
+			return types.DogPhoto{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("DeleteDogPhoto Commit Error: ", err)
+		return types.DogPhoto{}, err
+	}
+	log.Println("Success: DeleteDogPhoto Execution")
+	return p, nil
+}
+
+// ReorderDogPhotos queries database to renumber a dog's photos in the order of ids
+func (d *Db) ReorderDogPhotos(dogID graphql.ID, ids []graphql.ID) error {
+	log.Println("Starting: ReorderDogPhotos Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("ReorderDogPhotos Begin Error: ", err)
+		return err
+	}
+	defer tx.Rollback() // no-op once committed
+	did, _ := uuid.FromString(string(dogID))
+	var pus []uuid.UUID
+	GraphqlIDToUUID(ids, &pus)
+	// The deferred position constraint is checked at commit, after every photo has moved
+	res, err := tx.Exec(`UPDATE dog_photos p SET position = o.n - 1
+	FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, n)
+	WHERE p.dog_id = $1 AND p.id = o.id`, did, pq.Array(pus))
+	if err != nil {
+		log.Println("ReorderDogPhotos Execution Error: ", err)
+		return err
+	}
+	var total int64
+	if err := tx.QueryRow("SELECT count(*) FROM dog_photos WHERE dog_id=$1", did).Scan(&total); err != nil {
+		log.Println("ReorderDogPhotos Query Error: ", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n != int64(len(ids)) || total != n {
+		return store.ErrPhotoOrder
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("ReorderDogPhotos Commit Error: ", err)
+		return err
+	}
+	log.Println("Success: ReorderDogPhotos Execution")
+	return nil
+}
+
+// SetPrimaryDogPhoto queries database to make a photo the dog's profile image
+func (d *Db) SetPrimaryDogPhoto(dogID graphql.ID, id graphql.ID) error {
+	log.Println("Starting: SetPrimaryDogPhoto Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("SetPrimaryDogPhoto Begin Error: ", err)
+		return err
+	}
+	defer tx.Rollback() // no-op once committed
+	did, _ := uuid.FromString(string(dogID))
+	if err := setPrimaryPhoto(tx, did, id); err != nil {
+		log.Println("SetPrimaryDogPhoto Execution Error: ", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("SetPrimaryDogPhoto Commit Error: ", err)
+		return err
+	}
+	log.Println("Success: SetPrimaryDogPhoto Execution")
+	return nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/lib/pq"
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"strings"
+)
+
+// placeColumns lists the places columns read by scanPlace
+const placeColumns = `p.id, p.name, p.address, p.latitude, p.longitude, p.amenities`
+
+// scanPlace copies a places row into a Place
+func scanPlace(row scanner) (types.Place, error) {
+	var p types.Place
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.Address,
+		&p.Coordinates.Latitude,
+		&p.Coordinates.Longitude,
+		pq.Array(&p.Amenities),
+	)
+	return p, err
+}
+
+// InsertPlace queries database to add a place to the directory
+func (d *Db) InsertPlace(place types.Place) (types.Place, error) {
+	log.Println("Starting: InsertPlace Execution")
+	pid, _ := uuid.NewV1()
+	row := d.QueryRow(`INSERT INTO places AS p (id, name, address, latitude, longitude, amenities)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+placeColumns,
+		pid, place.Name, place.Address, place.Coordinates.Latitude, place.Coordinates.Longitude, pq.Array(place.Amenities))
+	p, err := scanPlace(row)
+	if err != nil {
+		log.Println("InsertPlace Execution Error: ", err)
+		return types.Place{}, err
+	}
+	log.Println("Success: InsertPlace Execution")
+	return p, nil
+}
+
+// UpdatePlace queries database to update a place, nil arguments keep their current value
+func (d *Db) UpdatePlace(id graphql.ID, name *string, address *string, coords *types.Coordinates, amenities *[]string) (types.Place, error) {
+	log.Println("Starting: UpdatePlace Execution")
+	var lat, lng *float64
+	if coords != nil {
+		lat, lng = &coords.Latitude, &coords.Longitude
+	}
+	var am interface{}
+	if amenities != nil {
+		am = pq.Array(*amenities)
+	}
+	pid, _ := uuid.FromString(string(id))
+	row := d.QueryRow(`UPDATE places AS p SET
+	name = COALESCE($1, name),
+	address = COALESCE($2, address),
+	latitude = COALESCE($3, latitude),
+	longitude = COALESCE($4, longitude),
+	amenities = COALESCE($5::text[], amenities)
+	WHERE id = $6 RETURNING `+placeColumns, name, address, lat, lng, am, pid)
+	p, err := scanPlace(row)
+	if err != nil {
+		log.Println("UpdatePlace Execution Error: ", err)
+		return types.Place{}, err
+	}
+	log.Println("Success: UpdatePlace Execution")
+	return p, nil
+}
+
+// DeletePlace queries database to remove a place, dates planned there keep their location text
+func (d *Db) DeletePlace(id graphql.ID) error {
+	log.Println("Starting: DeletePlace Execution")
+	pid, _ := uuid.FromString(string(id))
+	res, err := d.Exec("DELETE FROM places WHERE id=$1", pid)
+	if err != nil {
+		log.Println("DeletePlace Execution Error: ", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	log.Println("Success: DeletePlace Execution")
+	return nil
+}
+
+// GetPlacesByIDs is called by the place dataloader to fetch a batch of places
+func (d *Db) GetPlacesByIDs(ids []graphql.ID) (map[graphql.ID]types.Place, error) {
+	log.Println("Starting: GetPlacesByIDs Query")
+	var pus []uuid.UUID
+	GraphqlIDToUUID(ids, &pus)
+	rows, err := d.Query(`SELECT `+placeColumns+` FROM places p WHERE p.id = ANY($1)`, pq.Array(pus))
+	if err != nil {
+		log.Println("GetPlacesByIDs Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	places := map[graphql.ID]types.Place{}
+	for rows.Next() {
+		p, err := scanPlace(rows)
+		if err != nil {
+			log.Println("GetPlacesByIDs error scanning rows: ", err)
+			return places, err
+		}
+		places[p.ID] = p
+	}
+	log.Println("Success: GetPlacesByIDs Query")
+	return places, rows.Err()
+}
+
+// SearchPlaces queries database for places whose name or address contains search, within
+// radiusKm of near when given. Results are closest first, or by name without near.
+func (d *Db) SearchPlaces(near *types.Coordinates, radiusKm float64, search string, limit int) ([]store.PlaceMatch, error) {
+	log.Println("Starting: SearchPlaces Query")
+	conds := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) int {
+		args = append(args, v)
+		return len(args)
+	}
+	if search != "" {
+		n := arg(likeEscaper.Replace(search))
+		conds = append(conds, fmt.Sprintf("(p.name ILIKE '%%' || $%[1]d || '%%' OR p.address ILIKE '%%' || $%[1]d || '%%')", n))
+	}
+	distance, order := "NULL::double precision", "p.name, p.id"
+	if near != nil {
+		lat, lng := arg(near.Latitude), arg(near.Longitude)
+		distance = haversineSQL("p", lat, lng)
+		box := geo.BoundingBox(*near, radiusKm)
+		conds = append(conds,
+			fmt.Sprintf("p.latitude BETWEEN $%d AND $%d", arg(box.MinLat), arg(box.MaxLat)),
+			fmt.Sprintf("p.longitude BETWEEN $%d AND $%d", arg(box.MinLng), arg(box.MaxLng)),
+			fmt.Sprintf("%s <= $%d", distance, arg(radiusKm)))
+		order = "distance_km, p.id"
+	}
+	q := fmt.Sprintf(`SELECT %s, %s AS distance_km FROM places p WHERE %s ORDER BY %s LIMIT $%d`,
+		placeColumns, distance, strings.Join(conds, " AND "), order, arg(limit))
+	rows, err := d.Query(q, args...)
+	if err != nil {
+		log.Println("SearchPlaces Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var places []store.PlaceMatch
+	for rows.Next() {
+		var m store.PlaceMatch
+		p, err := scanPlace(scannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &m.DistanceKm)...)
+		}))
+		if err != nil {
+			log.Println("SearchPlaces error scanning rows: ", err)
+			return places, err
+		}
+		m.Place = p
+		places = append(places, m)
+	}
+	log.Println("Success: SearchPlaces Query")
+	return places, rows.Err()
+}
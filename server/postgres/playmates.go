@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GetPlaymateCandidates queries database for the dogs of other households living within radiusKm
+// of near that the dog has not liked or passed, closest first
+func (d *Db) GetPlaymateCandidates(dogID graphql.ID, near types.Coordinates, radiusKm float64, limit int) ([]store.Playmate, error) {
+	log.Println("Starting: GetPlaymateCandidates Query")
+	did, _ := uuid.FromString(string(dogID))
+	args := []interface{}{did}
+	arg := func(v interface{}) int {
+		args = append(args, v)
+		return len(args)
+	}
+	distance := haversineSQL("u", arg(near.Latitude), arg(near.Longitude))
+	box := geo.BoundingBox(near, radiusKm)
+	conds := []string{
+		"d.owner <> (SELECT owner FROM dogs WHERE id = $1)",
+		"NOT EXISTS (SELECT 1 FROM likes l WHERE l.from_dog = $1 AND l.to_dog = d.id)",
+		fmt.Sprintf("u.latitude BETWEEN $%d AND $%d", arg(box.MinLat), arg(box.MaxLat)),
+		fmt.Sprintf("u.longitude BETWEEN $%d AND $%d", arg(box.MinLng), arg(box.MaxLng)),
+		fmt.Sprintf("%s <= $%d", distance, arg(radiusKm)),
+	}
+	q := fmt.Sprintf(`SELECT
+	d.id,
+	d.name,
+	d.age,
+	d.breed,
+	d.owner,
+	d.profile_image,
+	%s,
+	u.latitude,
+	u.longitude
+	FROM dogs d
+	INNER JOIN users u ON u.id = d.owner
+	WHERE %s
+	ORDER BY %s, d.id
+	LIMIT $%d`, dogProfileColumns, strings.Join(conds, " AND "), distance, arg(limit))
+	rows, err := d.Query(q, args...)
+	if err != nil {
+		log.Println("GetPlaymateCandidates Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var playmates []store.Playmate
+	for rows.Next() {
+		var p store.Playmate
+		err = rows.Scan(append(append([]interface{}{
+			&p.Dog.ID,
+			&p.Dog.Name,
+			&p.Dog.Age,
+			&p.Dog.Breed,
+			&p.Dog.Owner,
+			&p.Dog.ProfileImageURL,
+		}, dogProfileDest(&p.Dog.DogProfile)...), &p.Home.Latitude, &p.Home.Longitude)...)
+		if err != nil {
+			log.Println("GetPlaymateCandidates error scanning rows: ", err)
+			return playmates, err
+		}
+		playmates = append(playmates, p)
+	}
+	log.Println("Success: GetPlaymateCandidates Query")
+	return playmates, rows.Err()
+}
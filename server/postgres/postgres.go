@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/store"
 	"github.com/raymondvooo/doggy-date-app/server/types"
 	uuid "github.com/satori/go.uuid"
 	"log"
@@ -18,6 +19,9 @@ type Db struct {
 	*sql.DB
 }
 
+// Db is the postgres backed store.Store
+var _ store.Store = (*Db)(nil)
+
 // NewConnection makes a new database using the connection string and
 // returns it, otherwise returns the error
 func NewConnection(connect string) (*Db, error) {
@@ -48,7 +52,8 @@ func (d *Db) GetUserByEmail(email string) (types.User, []types.Dog, error) {
 	d.name,
 	d.age,
 	d.breed,
-	d.profile_image
+	d.profile_image,
+	` + dogProfileColumns + `
 	FROM users u INNER JOIN dogs d ON u.id = d.owner
 	WHERE u.email = $1
 	ORDER BY u.name;`)
@@ -68,7 +73,7 @@ func (d *Db) GetUserByEmail(email string) (types.User, []types.Dog, error) {
 	}
 	for rows.Next() {
 		var joinDate time.Time
-		err = rows.Scan(
+		err = rows.Scan(append([]interface{}{
 			&u.ID,
 			&u.Name,
 			&u.Email,
@@ -79,12 +84,14 @@ func (d *Db) GetUserByEmail(email string) (types.User, []types.Dog, error) {
 			&dog.Age,
 			&dog.Breed,
 			&dog.ProfileImageURL,
-		)
+		}, dogProfileDest(&dog.DogProfile)...)...)
 		if err != nil {
 			log.Println(" GetUserByEmail error scanning rows: ", err)
 			return u, dogs, err
 		}
 		u.JoinDate = graphql.Time{Time: joinDate} // convert Time to graphql.Time
+		dog.Owner = u.ID
+		u.Dogs = append(u.Dogs, dog.ID)
 		dogs = append(dogs, dog)
 	}
 	log.Println("Success: GetUserByEmail Query")
@@ -104,7 +111,8 @@ func (d *Db) GetUserByID(id uuid.UUID) (types.User, []types.Dog, error) {
 	d.name,
 	d.age,
 	d.breed,
-	d.profile_image
+	d.profile_image,
+	` + dogProfileColumns + `
 	FROM users u INNER JOIN dogs d ON u.id = d.owner
 	WHERE u.id = $1
 	ORDER BY u.name;`)
@@ -123,7 +131,7 @@ func (d *Db) GetUserByID(id uuid.UUID) (types.User, []types.Dog, error) {
 	}
 	for rows.Next() {
 		var joinDate time.Time
-		err = rows.Scan(
+		err = rows.Scan(append([]interface{}{
 			&u.ID,
 			&u.Name,
 			&u.ProfileImageURL,
@@ -133,12 +141,14 @@ func (d *Db) GetUserByID(id uuid.UUID) (types.User, []types.Dog, error) {
 			&dog.Age,
 			&dog.Breed,
 			&dog.ProfileImageURL,
-		)
+		}, dogProfileDest(&dog.DogProfile)...)...)
 		if err != nil {
 			log.Println("GetUserByID error scanning rows: ", err)
 			return u, dogs, err
 		}
 		u.JoinDate = graphql.Time{Time: joinDate} // convert Time to graphql.Time
+		dog.Owner = u.ID
+		u.Dogs = append(u.Dogs, dog.ID)
 		dogs = append(dogs, dog)
 	}
 	log.Println("Success: GetUserByID Query")
@@ -157,7 +167,8 @@ func (d *Db) GetDogByID(id uuid.UUID) ([]types.Dog, types.User, error) {
 	d.profile_image,
 	u.id,
 	u.name,
-	u.profile_image
+	u.profile_image,
+	` + dogProfileColumns + `
 	FROM users u INNER JOIN dogs d ON u.id = d.owner
 	WHERE u.dogs::text LIKE '%' || $1 || '%'
 	ORDER BY d.id::text = $1 DESC;`)
@@ -175,7 +186,7 @@ func (d *Db) GetDogByID(id uuid.UUID) ([]types.Dog, types.User, error) {
 		return dogs, u, err
 	}
 	for rows.Next() {
-		err = rows.Scan(
+		err = rows.Scan(append([]interface{}{
 			&dog.ID,
 			&dog.Name,
 			&dog.Age,
@@ -184,160 +195,96 @@ func (d *Db) GetDogByID(id uuid.UUID) ([]types.Dog, types.User, error) {
 			&u.ID,
 			&u.Name,
 			&u.ProfileImageURL,
-		)
+		}, dogProfileDest(&dog.DogProfile)...)...)
 		if err != nil {
 			log.Println("GetDogByID error scanning rows: ", err)
 			return dogs, u, err
 		}
+		dog.Owner = u.ID
 		dogs = append(dogs, dog)
 	}
 	log.Println("Success: GetDogByID Query")
 	return dogs, u, nil
 }
 
-// GetDogsByArray is called within our dogs query for graphql
-func (d *Db) GetDogsByArray(dogIds []graphql.ID) (map[graphql.ID]types.Dog, map[graphql.ID]types.User, error) {
-	log.Println("Starting: GetDogsByArray Query")
-	// Prepare query, takes a id argument, protects from sql injection
-	stmt, err := d.Prepare(`SELECT
-		d.id,
-		d.name,
-		d.age,
-		d.breed,
-		d.profile_image,
-		u.id,
-		u.name,
-		u.profile_image,
-		u.join_date
-		FROM users u INNER JOIN dogs d ON u.id = d.owner
-		WHERE d.id= ANY($1);`)
-	if err != nil {
-		log.Println("GetDogsByArray Preparation Error: ", err)
-	}
-	defer stmt.Close()
-	var dog types.Dog
-	var dus []uuid.UUID
-	dogMap := map[graphql.ID]types.Dog{}
-	var u types.User
-	uMap := map[graphql.ID]types.User{}
-	GraphqlIDToUUID(dogIds, &dus)
-	// Make query with our stmt, passing in id argument
-	rows, err := stmt.Query(pq.Array(dus))
+// GetDoggyDatesPage is called within our doggydate query for graphql, dates matching
+// filter are ordered by date then id
+func (d *Db) GetDoggyDatesPage(page store.Page, filter store.DateFilter) ([]types.Date, bool, error) {
+	log.Println("Starting: GetDoggyDatesPage Query")
+	where, args := dateWhere(filter, 1)
+	cond, tail, keyArgs := keyset(page, "dd.date", "dd.id", "timestamptz", len(args)+1)
+	rows, err := d.Query(`SELECT `+dateColumns+`
+	FROM doggy_dates dd
+	WHERE `+where+` AND `+cond+` `+tail, append(args, keyArgs...)...)
 	if err != nil {
-		log.Println("GetDogsByArray Query Error: ", err)
+		log.Println("GetDoggyDatesPage Query Error: ", err)
+		return nil, false, err
 	}
-	// Copy the columns from row into the values pointed at by r (User)
+	defer rows.Close()
+	var dates []types.Date
 	for rows.Next() {
-		var joinDate time.Time
-		err = rows.Scan(
-			&dog.ID,
-			&dog.Name,
-			&dog.Age,
-			&dog.Breed,
-			&dog.ProfileImageURL,
-			&u.ID,
-			&u.Name,
-			&u.ProfileImageURL,
-			&joinDate, // readable Time type
-		)
+		date, err := scanDoggyDate(rows)
 		if err != nil {
-			log.Println("GetDogsByArray error scanning rows: ", err)
-			return dogMap, uMap, err
+			log.Println("GetDoggyDatesPage error scanning rows: ", err)
+			return dates, false, err
 		}
-		u.JoinDate = graphql.Time{Time: joinDate}
-		dogMap[dog.ID] = dog
-		uMap[u.ID] = u
+		dates = append(dates, date)
 	}
-	log.Println("Success: GetDogsByArray Query")
-	return dogMap, uMap, nil
+	n, more := trimPage(len(dates), page, func(i, j int) { dates[i], dates[j] = dates[j], dates[i] })
+	log.Println("Success: GetDoggyDatesPage Query")
+	return dates[:n], more, rows.Err()
 }
 
-// GetAllDoggyDates is called within our doggydate query for graphql
-func (d *Db) GetAllDoggyDates() (map[graphql.ID]types.Date, map[graphql.ID]types.User, map[graphql.ID]types.Dog, error) {
-	log.Println("Starting: GetAllDoggyDates Query")
-	// Prepare query, takes a id argument, protects from sql injection
-	stmt, err := d.Prepare(`SELECT
-	dd.*,
-	u.id,
-	u.name,
-	u.dogs,
-	u.profile_image,
-	u.join_date,
+// GetDogsPageByOwner is called within our user dogs connection for graphql, dogs are ordered by name then id
+func (d *Db) GetDogsPageByOwner(owner graphql.ID, page store.Page) ([]types.Dog, bool, error) {
+	log.Println("Starting: GetDogsPageByOwner Query")
+	cond, tail, args := keyset(page, "d.name", "d.id", "text", 2)
+	uid, _ := uuid.FromString(string(owner))
+	rows, err := d.Query(`SELECT
 	d.id,
 	d.name,
 	d.age,
 	d.breed,
-	d.profile_image
-	FROM doggy_dates dd
-		JOIN users u ON dd.user = u.id
-		JOIN dogs d ON d.owner = u.id;`)
-	if err != nil {
-		log.Println("GetAllDoggyDates Preparation Error: ", err)
-	}
-	defer stmt.Close()
-
-	// Make query with our stmt, passing in id argument
-	rows, err := stmt.Query()
+	d.owner,
+	d.profile_image,
+	`+dogProfileColumns+`
+	FROM dogs d
+	WHERE d.owner = $1 AND `+cond+` `+tail, append([]interface{}{uid}, args...)...)
 	if err != nil {
-		log.Println("GetAllDoggyDates Query Error: ", err)
+		log.Println("GetDogsPageByOwner Query Error: ", err)
+		return nil, false, err
 	}
-
-	var date types.Date
-	var u types.User
-	uMap := map[graphql.ID]types.User{}
-	var dog types.Dog
-	dogMap := map[graphql.ID]types.Dog{}
-	dates := map[graphql.ID]types.Date{}
-	// Copy the columns from row into the values pointed at by r (User)
+	defer rows.Close()
+	var dogs []types.Dog
 	for rows.Next() {
-		var createDate time.Time
-		var userJoinDate time.Time
-		var dateDogs []string
-		var userDogs []string
-		err = rows.Scan(
-			&date.ID,
-			&createDate, // readable Time type
-			&date.Description,
-			pq.Array(&dateDogs), // readable [] string type
-			&date.Location,
-			&date.User,
-			&u.ID,
-			&u.Name,
-			pq.Array(&userDogs), // readable [] string type
-			&u.ProfileImageURL,
-			&userJoinDate, // readable Time type
+		var dog types.Dog
+		err = rows.Scan(append([]interface{}{
 			&dog.ID,
 			&dog.Name,
 			&dog.Age,
 			&dog.Breed,
+			&dog.Owner,
 			&dog.ProfileImageURL,
-		)
+		}, dogProfileDest(&dog.DogProfile)...)...)
 		if err != nil {
-			log.Println("GetAllDoggyDates error scanning rows: ", err)
-			return dates, uMap, dogMap, err
+			log.Println("GetDogsPageByOwner error scanning rows: ", err)
+			return dogs, false, err
 		}
-		date.Date = graphql.Time{Time: createDate}    // convert Time to graphql.Time
-		u.JoinDate = graphql.Time{Time: userJoinDate} // convert Time to graphql.Time
-		date.Dogs = nil                               // reset values before reassigning
-		u.Dogs = nil                                  // reset values before reassigning
-		StringToGraphqlID(dateDogs, &date.Dogs)
-		StringToGraphqlID(userDogs, &u.Dogs)
-		dates[date.ID] = date
-		dogMap[dog.ID] = dog
-		uMap[u.ID] = u
+		dogs = append(dogs, dog)
 	}
-	log.Println("Success: GetAllDoggyDates Query")
-	return dates, uMap, dogMap, nil
+	n, more := trimPage(len(dogs), page, func(i, j int) { dogs[i], dogs[j] = dogs[j], dogs[i] })
+	log.Println("Success: GetDogsPageByOwner Query")
+	return dogs[:n], more, rows.Err()
 }
 
 // InsertUserDog queries database to insert user row
-func (d *Db) InsertUserDog(name string, email string, uImg string, dname string,
-	age int32, breed string, dImg string) (types.User, types.Dog, error) {
+func (d *Db) InsertUserDog(name string, email string, passwordHash string, uImg string, dname string,
+	age int32, breed string, dImg string, profile types.DogProfile) (types.User, types.Dog, error) {
 	log.Println("Starting: InsertUserDog Execution")
 	// Prepare query, takes arguments, protects from sql injection
 	stmt, err := d.Prepare(`WITH createAccount AS (
-		INSERT INTO users VALUES ($1, $2, $3, $4, $5, $6)
-	  ) INSERT INTO dogs VALUES ($7, $8, $9, $10, $11, $12);`)
+		INSERT INTO users VALUES ($1, $2, $3, $4, $5, $6, $7)
+	  ) INSERT INTO dogs VALUES ($8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22);`)
 	if err != nil {
 		log.Println("InsertUserDog Preparation Error: ", err)
 	}
@@ -346,7 +293,9 @@ func (d *Db) InsertUserDog(name string, email string, uImg string, dname string,
 	var di = []uuid.UUID{did}
 	uid, _ := uuid.NewV1() // Generate new uuid
 	joinDate := time.Now() // Generate timestamp
-	if _, err := stmt.Exec(uid, name, email, pq.Array(di), uImg, joinDate, did, dname, age, breed, uid, dImg); err != nil {
+	profile = insertProfile(profile)
+	if _, err := stmt.Exec(append([]interface{}{uid, name, email, pq.Array(di), uImg, joinDate, passwordHash,
+		did, dname, age, breed, uid, dImg}, dogProfileArgs(profile)...)...); err != nil {
 		log.Println("InsertUserDog Execution Error: ", err)
 		return types.User{}, types.Dog{}, err
 	}
@@ -363,49 +312,539 @@ func (d *Db) InsertUserDog(name string, email string, uImg string, dname string,
 			Age:             age,
 			Breed:           breed,
 			Owner:           graphql.ID(uid.String()),
-			ProfileImageURL: dImg}, nil
+			ProfileImageURL: dImg,
+			DogProfile:      profile}, nil
 }
 
-// InsertDoggyDate queries database to insert dog row
-func (d *Db) InsertDoggyDate(date graphql.Time, description string, dogIds []graphql.ID, location string, user graphql.ID) (types.Date, error) {
-	log.Println("Starting: InsertDoggyDate Execution")
-	// Prepare query, takes arguments, protects from sql injection
-	stmt, err := d.Prepare("INSERT INTO doggy_dates VALUES ($1, $2, $3, $4, $5, $6)")
+// UpdateUser queries database to update a user row, nil arguments keep their current value
+func (d *Db) UpdateUser(id graphql.ID, name *string, email *string, img *string, home *types.Coordinates) (types.User, error) {
+	log.Println("Starting: UpdateUser Execution")
+	stmt, err := d.Prepare(`UPDATE users SET
+	name = COALESCE($1, name),
+	email = COALESCE($2, email),
+	profile_image = COALESCE($3, profile_image),
+	latitude = COALESCE($4, latitude),
+	longitude = COALESCE($5, longitude)
+	WHERE id = $6
+	RETURNING id, name, email, profile_image, join_date, latitude, longitude;`)
 	if err != nil {
-		log.Println("InsertDoggyDateDog Preparation Error: ", err)
+		log.Println("UpdateUser Preparation Error: ", err)
+		return types.User{}, err
 	}
 	defer stmt.Close()
+	var u types.User
+	var joinDate time.Time
+	var lat, lng *float64
+	if home != nil {
+		lat, lng = &home.Latitude, &home.Longitude
+	}
+	uid, _ := uuid.FromString(string(id))
+	err = stmt.QueryRow(name, email, img, lat, lng, uid).Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.ProfileImageURL,
+		&joinDate, // readable Time type
+		&lat,
+		&lng,
+	)
+	if err != nil {
+		log.Println("UpdateUser Execution Error: ", err)
+		return types.User{}, err
+	}
+	u.JoinDate = graphql.Time{Time: joinDate} // convert Time to graphql.Time
+	u.Home = coordinates(lat, lng)
+	log.Println("Success: UpdateUser Execution")
+	return u, nil
+}
+
+// DeleteUser queries database to delete a user along with their sessions, dogs and doggy dates.
+// The user's dogs are also taken off doggy dates planned by other users.
+func (d *Db) DeleteUser(id graphql.ID) (store.Objects, error) {
+	log.Println("Starting: DeleteUser Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("DeleteUser Begin Error: ", err)
+		return store.Objects{}, err
+	}
+	defer tx.Rollback() // no-op once committed
+	uid, _ := uuid.FromString(string(id))
+	// The images, dog_photos and vaccinations rows cascade, so read their keys first
+	var objs store.Objects
+	objs.Images, err = selectImages(tx, "images", "user_id=$1 OR dog_id IN (SELECT id FROM dogs WHERE owner=$1)", uid)
+	if err != nil {
+		log.Println("DeleteUser Query Error: ", err)
+		return store.Objects{}, err
+	}
+	photos, err := selectImages(tx, "dog_photos", "dog_id IN (SELECT id FROM dogs WHERE owner=$1)", uid)
+	if err != nil {
+		log.Println("DeleteUser Query Error: ", err)
+		return store.Objects{}, err
+	}
+	objs.Images = append(objs.Images, photos...)
+	if err := tx.QueryRow(`SELECT ARRAY(SELECT certificate_key FROM vaccinations
+	WHERE certificate_key<>'' AND dog_id IN (SELECT id FROM dogs WHERE owner=$1))`, uid).Scan(pq.Array(&objs.Certificates)); err != nil {
+		log.Println("DeleteUser Query Error: ", err)
+		return store.Objects{}, err
+	}
+	steps := []string{
+		"DELETE FROM sessions WHERE user_id=$1",
+		`DELETE FROM invitations WHERE dog_id IN (SELECT id FROM dogs WHERE owner=$1)
+			OR date_id IN (SELECT id FROM doggy_dates WHERE "user"=$1)`,
+		`UPDATE doggy_dates SET dogs = ARRAY(
+			SELECT x FROM unnest(dogs) x WHERE x NOT IN (SELECT id FROM dogs WHERE owner=$1)
+		) WHERE dogs && ARRAY(SELECT id FROM dogs WHERE owner=$1)`,
+		`DELETE FROM doggy_dates WHERE "user"=$1`,
+		"DELETE FROM dogs WHERE owner=$1",
+		"DELETE FROM users WHERE id=$1",
+	}
+	for _, q := range steps {
+		if _, err := tx.Exec(q, uid); err != nil {
+			log.Println("DeleteUser Execution Error: ", err)
+			return store.Objects{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("DeleteUser Commit Error: ", err)
+		return store.Objects{}, err
+	}
+	log.Println("Success: DeleteUser Execution")
+	return objs, nil
+}
+
+// InsertDog queries database to insert a dog row and append it to its owner's dogs
+func (d *Db) InsertDog(owner graphql.ID, name string, age int32, breed string, img string, profile types.DogProfile) (types.Dog, error) {
+	log.Println("Starting: InsertDog Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("InsertDog Begin Error: ", err)
+		return types.Dog{}, err
+	}
+	defer tx.Rollback() // no-op once committed
+	did, _ := uuid.NewV1()
+	uid, _ := uuid.FromString(string(owner))
+	profile = insertProfile(profile)
+	if _, err := tx.Exec("INSERT INTO dogs VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)",
+		append([]interface{}{did, name, age, breed, uid, img}, dogProfileArgs(profile)...)...); err != nil {
+		log.Println("InsertDog Execution Error: ", err)
+		return types.Dog{}, err
+	}
+	if _, err := tx.Exec("UPDATE users SET dogs = array_append(dogs, $1) WHERE id=$2", did, uid); err != nil {
+		log.Println("InsertDog Execution Error: ", err)
+		return types.Dog{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("InsertDog Commit Error: ", err)
+		return types.Dog{}, err
+	}
+	log.Println("Success: InsertDog Execution")
+	return types.Dog{
+		ID:              graphql.ID(did.String()),
+		Name:            name,
+		Age:             age,
+		Breed:           breed,
+		Owner:           owner,
+		ProfileImageURL: img,
+		DogProfile:      profile}, nil
+}
+
+// UpdateDog queries database to update a dog row, nil arguments keep their current value
+func (d *Db) UpdateDog(id graphql.ID, name *string, age *int32, breed *string, profile types.DogProfile) (types.Dog, error) {
+	log.Println("Starting: UpdateDog Execution")
+	stmt, err := d.Prepare(`UPDATE dogs AS d SET
+	name = COALESCE($1, name),
+	age = COALESCE($2, age),
+	breed = COALESCE($3, breed),
+	size = COALESCE($5, size),
+	weight_kg = COALESCE($6, weight_kg),
+	sex = COALESCE($7, sex),
+	neutered = COALESCE($8, neutered),
+	energy_level = COALESCE($9, energy_level),
+	play_styles = COALESCE($10, play_styles),
+	good_with_small_dogs = COALESCE($11, good_with_small_dogs),
+	good_with_puppies = COALESCE($12, good_with_puppies),
+	good_with_people = COALESCE($13, good_with_people)
+	WHERE id = $4
+	RETURNING d.id, d.name, d.age, d.breed, d.owner, d.profile_image, ` + dogProfileColumns + `;`)
+	if err != nil {
+		log.Println("UpdateDog Preparation Error: ", err)
+		return types.Dog{}, err
+	}
+	defer stmt.Close()
+	var dog types.Dog
+	did, _ := uuid.FromString(string(id))
+	err = stmt.QueryRow(append([]interface{}{name, age, breed, did}, dogProfileArgs(profile)...)...).Scan(append([]interface{}{
+		&dog.ID,
+		&dog.Name,
+		&dog.Age,
+		&dog.Breed,
+		&dog.Owner,
+		&dog.ProfileImageURL,
+	}, dogProfileDest(&dog.DogProfile)...)...)
+	if err != nil {
+		log.Println("UpdateDog Execution Error: ", err)
+		return types.Dog{}, err
+	}
+	log.Println("Success: UpdateDog Execution")
+	return dog, nil
+}
+
+// DeleteDog queries database to delete a dog row and remove it from its owner and doggy dates
+func (d *Db) DeleteDog(id graphql.ID) (graphql.ID, store.Objects, error) {
+	log.Println("Starting: DeleteDog Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("DeleteDog Begin Error: ", err)
+		return "", store.Objects{}, err
+	}
+	defer tx.Rollback() // no-op once committed
+	did, _ := uuid.FromString(string(id))
+	var owner graphql.ID
+	// Lock the owner so concurrent deletes cannot each see another dog left
+	if err := tx.QueryRow(`SELECT u.id FROM users u JOIN dogs d ON d.owner = u.id WHERE d.id=$1
+	FOR UPDATE OF u`, did).Scan(&owner); err != nil {
+		log.Println("DeleteDog Query Error: ", err)
+		return "", store.Objects{}, err
+	}
+	var count int
+	if err := tx.QueryRow("SELECT count(*) FROM dogs WHERE owner=$1", string(owner)).Scan(&count); err != nil {
+		log.Println("DeleteDog Query Error: ", err)
+		return "", store.Objects{}, err
+	}
+	if count <= 1 {
+		return "", store.Objects{}, store.ErrLastDog
+	}
+	// The images, dog_photos and vaccinations rows cascade, so read their keys first
+	var objs store.Objects
+	objs.Images, err = selectImages(tx, "images", "dog_id=$1", did)
+	if err != nil {
+		log.Println("DeleteDog Query Error: ", err)
+		return "", store.Objects{}, err
+	}
+	photos, err := selectImages(tx, "dog_photos", "dog_id=$1", did)
+	if err != nil {
+		log.Println("DeleteDog Query Error: ", err)
+		return "", store.Objects{}, err
+	}
+	objs.Images = append(objs.Images, photos...)
+	if err := tx.QueryRow(`SELECT ARRAY(SELECT certificate_key FROM vaccinations
+	WHERE certificate_key<>'' AND dog_id=$1)`, did).Scan(pq.Array(&objs.Certificates)); err != nil {
+		log.Println("DeleteDog Query Error: ", err)
+		return "", store.Objects{}, err
+	}
+	if _, err := tx.Exec("DELETE FROM dogs WHERE id=$1", did); err != nil {
+		log.Println("DeleteDog Execution Error: ", err)
+		return "", store.Objects{}, err
+	}
+	if _, err := tx.Exec("UPDATE users SET dogs = array_remove(dogs, $1) WHERE id=$2", did, string(owner)); err != nil {
+		log.Println("DeleteDog Execution Error: ", err)
+		return "", store.Objects{}, err
+	}
+	if _, err := tx.Exec("DELETE FROM invitations WHERE dog_id=$1", did); err != nil {
+		log.Println("DeleteDog Execution Error: ", err)
+		return "", store.Objects{}, err
+	}
+	if _, err := tx.Exec("UPDATE doggy_dates SET dogs = array_remove(dogs, $1) WHERE $1 = ANY(dogs)", did); err != nil {
+		log.Println("DeleteDog Execution Error: ", err)
+		return "", store.Objects{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("DeleteDog Commit Error: ", err)
+		return "", store.Objects{}, err
+	}
+	log.Println("Success: DeleteDog Execution")
+	return owner, objs, nil
+}
+
+// dogProfileColumns lists the dogs profile columns read by dogProfileDest, in table order
+const dogProfileColumns = `d.size, d.weight_kg, d.sex, d.neutered, d.energy_level, d.play_styles,
+	d.good_with_small_dogs, d.good_with_puppies, d.good_with_people`
+
+// dogProfileDest returns the scan destinations for dogProfileColumns
+func dogProfileDest(p *types.DogProfile) []interface{} {
+	return []interface{}{
+		&p.Size,
+		&p.WeightKg,
+		&p.Sex,
+		&p.Neutered,
+		&p.EnergyLevel,
+		pq.Array(&p.PlayStyles),
+		&p.GoodWithSmallDogs,
+		&p.GoodWithPuppies,
+		&p.GoodWithPeople,
+	}
+}
+
+// dogProfileArgs returns the profile as query arguments in table order, nil play styles are NULL
+func dogProfileArgs(p types.DogProfile) []interface{} {
+	var styles interface{}
+	if p.PlayStyles != nil {
+		styles = pq.Array(p.PlayStyles)
+	}
+	return []interface{}{p.Size, p.WeightKg, p.Sex, p.Neutered, p.EnergyLevel, styles,
+		p.GoodWithSmallDogs, p.GoodWithPuppies, p.GoodWithPeople}
+}
+
+// insertProfile fills in the empty play styles a new dogs row defaults to
+func insertProfile(p types.DogProfile) types.DogProfile {
+	if p.PlayStyles == nil {
+		p.PlayStyles = []string{}
+	}
+	return p
+}
+
+// InsertDoggyDate queries database to insert a doggy date row and invite each of the invited dogs
+// in one transaction, the id and status are assigned here
+func (d *Db) InsertDoggyDate(date types.Date, invited []graphql.ID) (types.Date, error) {
+	log.Println("Starting: InsertDoggyDate Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("InsertDoggyDate Begin Error: ", err)
+		return types.Date{}, err
+	}
+	defer tx.Rollback() // no-op once committed
 
 	var dus []uuid.UUID
-	GraphqlIDToUUID(dogIds, &dus)
+	GraphqlIDToUUID(date.Dogs, &dus)
 	did, _ := uuid.NewV1()
-	uid, _ := uuid.FromString(string(user))
-	gDate := date.Local() // convert graphql.Time to golang Time
-	if _, err := stmt.Exec(did, gDate, description, pq.Array(dus), location, uid); err != nil {
+	uid, _ := uuid.FromString(string(date.User))
+	gDate := date.Date.Local() // convert graphql.Time to golang Time
+	var lat, lng *float64
+	if date.Coordinates != nil {
+		lat, lng = &date.Coordinates.Latitude, &date.Coordinates.Longitude
+	}
+	required := date.RequiresVaccinations
+	if required == nil {
+		required = []string{}
+	}
+	var pid *uuid.UUID
+	if date.Place != "" {
+		id, _ := uuid.FromString(string(date.Place))
+		pid = &id
+	}
+	if _, err := tx.Exec("INSERT INTO doggy_dates VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+		did, gDate, date.Description, pq.Array(dus), date.Location, uid, types.DateStatusPlanned, "",
+		lat, lng, date.PlaceName, pid, pq.Array(required)); err != nil {
 		log.Println("InsertDoggyDate Execution Error: ", err)
 		return types.Date{}, err
 	}
+	for _, dogID := range invited {
+		if _, err := insertInvitation(tx, graphql.ID(did.String()), dogID, date.User); err != nil {
+			log.Println("InsertDoggyDate Execution Error: ", err)
+			return types.Date{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("InsertDoggyDate Commit Error: ", err)
+		return types.Date{}, err
+	}
 	log.Println("Success: InsertDoggyDateDog Execution")
-	return types.Date{ID: graphql.ID(did.String()), Date: date, Description: description, Dogs: dogIds, Location: location, User: user}, nil
+	date.ID = graphql.ID(did.String())
+	date.Status = types.DateStatusPlanned
+	date.CancelReason = ""
+	return date, nil
 }
 
-// UpdateProfilePic queries database if email exists
-func (d *Db) UpdateProfilePic(tableType string, id graphql.ID, imgURL string) (bool, error) {
-	// Prepare query, takes arguments, protects from sql injection
-	q := fmt.Sprintf("UPDATE %s SET profile_image=$1 WHERE id=$2", tableType)
-	log.Println("Starting: UpdateProfilePic Execution")
-	stmt, err := d.Prepare(q)
+// dateColumns lists the doggy_dates columns read by scanDoggyDate
+const dateColumns = `id, date, description, dogs, location, "user", status, cancel_reason, latitude, longitude, place_name, place_id,
+	requires_vaccinations`
+
+// dateReturning reads back an inserted or updated doggy_dates row for scanDoggyDate
+const dateReturning = `RETURNING ` + dateColumns
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanDoggyDate copies a doggy_dates row into a Date
+func scanDoggyDate(row scanner) (types.Date, error) {
+	var date types.Date
+	var createDate time.Time
+	var dateDogs []string
+	var lat, lng *float64
+	var place *string
+	err := row.Scan(
+		&date.ID,
+		&createDate, // readable Time type
+		&date.Description,
+		pq.Array(&dateDogs), // readable [] string type
+		&date.Location,
+		&date.User,
+		&date.Status,
+		&date.CancelReason,
+		&lat,
+		&lng,
+		&date.PlaceName,
+		&place,
+		pq.Array(&date.RequiresVaccinations),
+	)
 	if err != nil {
-		log.Println("UpdateProfilePic Preparation Error: ", err)
+		return types.Date{}, err
+	}
+	if place != nil {
+		date.Place = graphql.ID(*place)
+	}
+	date.Date = graphql.Time{Time: createDate} // convert Time to graphql.Time
+	date.Coordinates = coordinates(lat, lng)
+	StringToGraphqlID(dateDogs, &date.Dogs)
+	return date, nil
+}
+
+// coordinates returns the position scanned from a nullable latitude and longitude pair
+func coordinates(lat, lng *float64) *types.Coordinates {
+	if lat == nil || lng == nil {
+		return nil
+	}
+	return &types.Coordinates{Latitude: *lat, Longitude: *lng}
+}
+
+// UpdateDoggyDate queries database to update a doggy date, nil arguments keep their current value
+func (d *Db) UpdateDoggyDate(id graphql.ID, date *graphql.Time, description *string, location *string, status *string) (types.Date, error) {
+	log.Println("Starting: UpdateDoggyDate Execution")
+	stmt, err := d.Prepare(`UPDATE doggy_dates SET
+	date = COALESCE($1, date),
+	description = COALESCE($2, description),
+	location = COALESCE($3, location),
+	status = COALESCE($4, status)
+	WHERE id = $5 ` + dateReturning)
+	if err != nil {
+		log.Println("UpdateDoggyDate Preparation Error: ", err)
+		return types.Date{}, err
 	}
 	defer stmt.Close()
-	uid, _ := uuid.FromString(string(id))
-	if _, err := stmt.Exec(imgURL, uid); err != nil {
-		log.Println("UpdateProfilePic Execution Error: ", err)
-		return false, err
+	var gDate *time.Time
+	if date != nil {
+		t := date.Local() // convert graphql.Time to golang Time
+		gDate = &t
 	}
-	log.Println("Success: UpdateProfilePic Execution")
-	return true, nil
+	did, _ := uuid.FromString(string(id))
+	dd, err := scanDoggyDate(stmt.QueryRow(gDate, description, location, status, did))
+	if err != nil {
+		log.Println("UpdateDoggyDate Execution Error: ", err)
+		return types.Date{}, err
+	}
+	log.Println("Success: UpdateDoggyDate Execution")
+	return dd, nil
+}
+
+// CancelDoggyDate queries database to mark a doggy date cancelled, the row is kept
+func (d *Db) CancelDoggyDate(id graphql.ID, reason string) (types.Date, error) {
+	log.Println("Starting: CancelDoggyDate Execution")
+	stmt, err := d.Prepare(`UPDATE doggy_dates SET status = $1, cancel_reason = $2 WHERE id = $3 ` + dateReturning)
+	if err != nil {
+		log.Println("CancelDoggyDate Preparation Error: ", err)
+		return types.Date{}, err
+	}
+	defer stmt.Close()
+	did, _ := uuid.FromString(string(id))
+	dd, err := scanDoggyDate(stmt.QueryRow(types.DateStatusCancelled, reason, did))
+	if err != nil {
+		log.Println("CancelDoggyDate Execution Error: ", err)
+		return types.Date{}, err
+	}
+	log.Println("Success: CancelDoggyDate Execution")
+	return dd, nil
+}
+
+// GetDoggyDateByID queries database for a single doggy date
+func (d *Db) GetDoggyDateByID(id graphql.ID) (types.Date, error) {
+	log.Println("Starting: GetDoggyDateByID Query")
+	stmt, err := d.Prepare(`SELECT ` + dateColumns + `
+	FROM doggy_dates WHERE id = $1`)
+	if err != nil {
+		log.Println("GetDoggyDateByID Preparation Error: ", err)
+		return types.Date{}, err
+	}
+	defer stmt.Close()
+	did, _ := uuid.FromString(string(id))
+	dd, err := scanDoggyDate(stmt.QueryRow(did))
+	if err != nil {
+		log.Println("GetDoggyDateByID Query Error: ", err)
+		return types.Date{}, err
+	}
+	log.Println("Success: GetDoggyDateByID Query")
+	return dd, nil
+}
+
+// DeleteDoggyDate queries database to delete a doggy date row and its invitations
+func (d *Db) DeleteDoggyDate(id graphql.ID) error {
+	log.Println("Starting: DeleteDoggyDate Execution")
+	tx, err := d.Begin()
+	if err != nil {
+		log.Println("DeleteDoggyDate Begin Error: ", err)
+		return err
+	}
+	defer tx.Rollback() // no-op once committed
+	did, _ := uuid.FromString(string(id))
+	if _, err := tx.Exec("DELETE FROM invitations WHERE date_id=$1", did); err != nil {
+		log.Println("DeleteDoggyDate Execution Error: ", err)
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM doggy_dates WHERE id=$1", did); err != nil {
+		log.Println("DeleteDoggyDate Execution Error: ", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("DeleteDoggyDate Commit Error: ", err)
+		return err
+	}
+	log.Println("Success: DeleteDoggyDate Execution")
+	return nil
+}
+
+// GetDogOwners queries database for the owner of each dog in dogIds
+func (d *Db) GetDogOwners(dogIds []graphql.ID) (map[graphql.ID]graphql.ID, error) {
+	log.Println("Starting: GetDogOwners Query")
+	stmt, err := d.Prepare("SELECT id, owner FROM dogs WHERE id = ANY($1)")
+	if err != nil {
+		log.Println("GetDogOwners Preparation Error: ", err)
+		return nil, err
+	}
+	defer stmt.Close()
+	var dus []uuid.UUID
+	GraphqlIDToUUID(dogIds, &dus)
+	rows, err := stmt.Query(pq.Array(dus))
+	if err != nil {
+		log.Println("GetDogOwners Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	owners := map[graphql.ID]graphql.ID{}
+	for rows.Next() {
+		var id, owner graphql.ID
+		if err := rows.Scan(&id, &owner); err != nil {
+			log.Println("GetDogOwners error scanning rows: ", err)
+			return owners, err
+		}
+		owners[id] = owner
+	}
+	log.Println("Success: GetDogOwners Query")
+	return owners, nil
+}
+
+// GetDateOrganizer queries database for the user who planned a doggy date
+func (d *Db) GetDateOrganizer(id graphql.ID) (graphql.ID, error) {
+	log.Println("Starting: GetDateOrganizer Query")
+	stmt, err := d.Prepare(`SELECT "user" FROM doggy_dates WHERE id=$1`)
+	if err != nil {
+		log.Println("GetDateOrganizer Preparation Error: ", err)
+		return "", err
+	}
+	defer stmt.Close()
+	var organizer graphql.ID
+	did, _ := uuid.FromString(string(id))
+	if err := stmt.QueryRow(did).Scan(&organizer); err != nil {
+		log.Println("GetDateOrganizer Query Error: ", err)
+		return "", err
+	}
+	log.Println("Success: GetDateOrganizer Query")
+	return organizer, nil
 }
 
 // CheckIDExists queries database if user or dog ID exists
@@ -428,6 +867,121 @@ func (d *Db) CheckIDExists(tableType string, id graphql.ID) (bool, error) {
 	return true, nil
 }
 
+// GetUserCredentials queries database for the user ID and password hash belonging to email
+func (d *Db) GetUserCredentials(email string) (graphql.ID, string, error) {
+	log.Println("Starting: GetUserCredentials Query")
+	stmt, err := d.Prepare("SELECT id, password FROM users WHERE email=$1")
+	if err != nil {
+		log.Println("GetUserCredentials Preparation Error: ", err)
+		return "", "", err
+	}
+	defer stmt.Close()
+	var uid graphql.ID
+	var hash string
+	if err := stmt.QueryRow(email).Scan(&uid, &hash); err != nil {
+		log.Println("GetUserCredentials Query Error: ", err)
+		return "", "", err
+	}
+	log.Println("Success: GetUserCredentials Query")
+	return uid, hash, nil
+}
+
+// InsertSession queries database to insert a session row for a logged in user
+func (d *Db) InsertSession(tokenHash string, user graphql.ID, expiresAt time.Time) error {
+	log.Println("Starting: InsertSession Execution")
+	stmt, err := d.Prepare("INSERT INTO sessions VALUES ($1, $2, $3, $4)")
+	if err != nil {
+		log.Println("InsertSession Preparation Error: ", err)
+		return err
+	}
+	defer stmt.Close()
+	uid, _ := uuid.FromString(string(user))
+	if _, err := stmt.Exec(tokenHash, uid, time.Now(), expiresAt); err != nil {
+		log.Println("InsertSession Execution Error: ", err)
+		return err
+	}
+	log.Println("Success: InsertSession Execution")
+	return nil
+}
+
+// GetSession queries database for the user owning an unexpired session token
+func (d *Db) GetSession(tokenHash string) (types.User, time.Time, error) {
+	log.Println("Starting: GetSession Query")
+	stmt, err := d.Prepare(`SELECT
+	u.id,
+	u.name,
+	u.email,
+	u.profile_image,
+	u.join_date,
+	u.is_admin,
+	s.expires_at
+	FROM sessions s INNER JOIN users u ON s.user_id = u.id
+	WHERE s.token = $1 AND s.expires_at > $2;`)
+	if err != nil {
+		log.Println("GetSession Preparation Error: ", err)
+		return types.User{}, time.Time{}, err
+	}
+	defer stmt.Close()
+	var u types.User
+	var joinDate time.Time
+	var expiresAt time.Time
+	err = stmt.QueryRow(tokenHash, time.Now()).Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.ProfileImageURL,
+		&joinDate, // readable Time type
+		&u.IsAdmin,
+		&expiresAt,
+	)
+	if err != nil {
+		log.Println("GetSession Query Error: ", err)
+		return types.User{}, time.Time{}, err
+	}
+	u.JoinDate = graphql.Time{Time: joinDate} // convert Time to graphql.Time
+	log.Println("Success: GetSession Query")
+	return u, expiresAt, nil
+}
+
+// RotateSession queries database to swap a session token for a new one with a new expiry
+func (d *Db) RotateSession(oldHash string, newHash string, expiresAt time.Time) error {
+	log.Println("Starting: RotateSession Execution")
+	stmt, err := d.Prepare("UPDATE sessions SET token=$1, expires_at=$2 WHERE token=$3 AND expires_at > $4")
+	if err != nil {
+		log.Println("RotateSession Preparation Error: ", err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(newHash, expiresAt, oldHash, time.Now())
+	if err != nil {
+		log.Println("RotateSession Execution Error: ", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		log.Println("RotateSession Execution: session not found")
+		return sql.ErrNoRows
+	}
+	log.Println("Success: RotateSession Execution")
+	return nil
+}
+
+// DeleteSession queries database to revoke a session token
+func (d *Db) DeleteSession(tokenHash string) error {
+	log.Println("Starting: DeleteSession Execution")
+	stmt, err := d.Prepare("DELETE FROM sessions WHERE token=$1")
+	if err != nil {
+		log.Println("DeleteSession Preparation Error: ", err)
+		return err
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec(tokenHash); err != nil {
+		log.Println("DeleteSession Execution Error: ", err)
+		return err
+	}
+	log.Println("Success: DeleteSession Execution")
+	return nil
+}
+
 // CheckEmailExists queries database if email exists
 func (d *Db) CheckEmailExists(email string) (bool, error) {
 	stmt, err := d.Prepare("SELECT email FROM users WHERE email=$1")
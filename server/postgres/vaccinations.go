@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"github.com/graph-gophers/graphql-go"
+	"github.com/lib/pq"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"time"
+)
+
+// vaccinationColumns lists the vaccinations columns read by scanVaccination
+const vaccinationColumns = `id, dog_id, vaccine, administered_on, expires_on, certificate_key, certificate_content_type,
+	created_at`
+
+// dateOnly formats t as a postgres date
+const dateOnly = "2006-01-02"
+
+// scanVaccination reads a vaccinations row selected with vaccinationColumns
+func scanVaccination(row scanner) (types.Vaccination, error) {
+	var v types.Vaccination
+	var administeredOn, createdAt time.Time
+	var expiresOn *time.Time
+	err := row.Scan(&v.ID, &v.Dog, &v.Vaccine, &administeredOn, &expiresOn, &v.CertificateKey, &v.CertificateContentType,
+		&createdAt)
+	v.AdministeredOn = graphql.Time{Time: administeredOn}
+	if expiresOn != nil {
+		v.ExpiresOn = &graphql.Time{Time: *expiresOn}
+	}
+	v.CreatedAt = graphql.Time{Time: createdAt}
+	return v, err
+}
+
+// GetVaccinations is called by the vaccinations dataloader, each dog's records are ordered
+// by when they were administered
+func (d *Db) GetVaccinations(dogIds []graphql.ID) (map[graphql.ID][]types.Vaccination, error) {
+	log.Println("Starting: GetVaccinations Query")
+	var dus []uuid.UUID
+	GraphqlIDToUUID(dogIds, &dus)
+	rows, err := d.Query(`SELECT `+vaccinationColumns+`
+	FROM vaccinations
+	WHERE dog_id = ANY($1)
+	ORDER BY dog_id, administered_on, id;`, pq.Array(dus))
+	if err != nil {
+		log.Println("GetVaccinations Query Error: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	records := map[graphql.ID][]types.Vaccination{}
+	for rows.Next() {
+		v, err := scanVaccination(rows)
+		if err != nil {
+			log.Println("GetVaccinations error scanning rows: ", err)
+			return records, err
+		}
+		records[v.Dog] = append(records[v.Dog], v)
+	}
+	log.Println("Success: GetVaccinations Query")
+	return records, rows.Err()
+}
+
+// InsertVaccination queries database to insert a vaccinations row
+func (d *Db) InsertVaccination(v types.Vaccination) (types.Vaccination, error) {
+	log.Println("Starting: InsertVaccination Execution")
+	vid, _ := uuid.FromString(string(v.ID))
+	did, _ := uuid.FromString(string(v.Dog))
+	var expiresOn *string
+	if v.ExpiresOn != nil {
+		e := v.ExpiresOn.Time.Format(dateOnly)
+		expiresOn = &e
+	}
+	v, err := scanVaccination(d.QueryRow(`INSERT INTO vaccinations VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING `+vaccinationColumns, vid, did, v.Vaccine, v.AdministeredOn.Time.Format(dateOnly), expiresOn,
+		v.CertificateKey, v.CertificateContentType, v.CreatedAt.Time))
+	if err != nil {
+		log.Println("InsertVaccination Execution Error: ", err)
+		return types.Vaccination{}, err
+	}
+	log.Println("Success: InsertVaccination Execution")
+	return v, nil
+}
+
+// DeleteVaccination queries database to delete one of a dog's vaccinations rows
+func (d *Db) DeleteVaccination(dogID graphql.ID, id graphql.ID) (types.Vaccination, error) {
+	log.Println("Starting: DeleteVaccination Execution")
+	did, _ := uuid.FromString(string(dogID))
+	vid, _ := uuid.FromString(string(id))
+	v, err := scanVaccination(d.QueryRow(`DELETE FROM vaccinations WHERE dog_id=$1 AND id=$2
+	RETURNING `+vaccinationColumns, did, vid))
+	if err != nil {
+		log.Println("DeleteVaccination Execution Error: ", err)
+		return types.Vaccination{}, err
+	}
+	log.Println("Success: DeleteVaccination Execution")
+	return v, nil
+}
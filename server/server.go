@@ -13,10 +13,12 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/go-chi/render"
 	"github.com/graph-gophers/graphql-go/relay"
-	"github.com/minio/minio-go"
 	"github.com/raymondvooo/doggy-date-app/server/api"
+	"github.com/raymondvooo/doggy-date-app/server/auth"
 	"github.com/raymondvooo/doggy-date-app/server/gql"
+	"github.com/raymondvooo/doggy-date-app/server/loader"
 	"github.com/raymondvooo/doggy-date-app/server/postgres"
+	"github.com/raymondvooo/doggy-date-app/server/storage"
 )
 
 func main() {
@@ -25,7 +27,6 @@ func main() {
 	if !exists {
 		port = "8080"
 	}
-	log.Printf("Starting server on port %s\n", port)
 
 	//if developing locally
 	localEnvError := godotenv.Load()
@@ -33,6 +34,13 @@ func main() {
 		log.Println("Error loading .env file")
 	}
 
+	// `server migrate up|down|status` manages the database schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+	log.Printf("Starting server on port %s\n", port)
+
 	// creds := credentials.NewStaticCredentials(os.Getenv("AWSAccessKeyId"), os.Getenv("AWSSecretKey"), "")
 	// sess := session.Must(session.NewSession(&aws.Config{
 	// 	Region:      aws.String("us-west-1"),
@@ -40,8 +48,18 @@ func main() {
 	// cfg := aws.NewConfig().WithRegion("us-west-1").WithCredentials(creds).WithLogLevel(aws.LogDebug)
 	// s3b := s3.New(sess, cfg)
 
-	// creates a new AWS S3 client instance
-	minioClient, err := minio.NewWithRegion("s3.amazonaws.com", os.Getenv("AWSAccessKeyId"), os.Getenv("AWSSecretKey"), true, "us-west-1")
+	// creates the profile image store, S3 by default or local disk with IMAGE_STORE=local
+	images, err := storage.New(storage.ConfigFromEnv())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// vaccination certificates go to a private store that is never served publicly
+	docConfig, err := storage.DocumentConfigFromEnv()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	documents, err := storage.New(docConfig)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -59,7 +77,7 @@ func main() {
 	}
 
 	//Parses graphql schema string into Schema object
-	schema := graphql.MustParseSchema(gqlSchema, &gql.Resolver{Db: db})
+	schema := graphql.MustParseSchema(gqlSchema, &gql.Resolver{Db: db, Images: images, Documents: documents})
 
 	router := chi.NewRouter()
 	// Add some middleware to our router
@@ -82,6 +100,7 @@ func main() {
 		middleware.DefaultCompress, // compress results, mostly gzipping assets and json
 		middleware.StripSlashes,    // match paths with a trailing slash, strip it, and continue routing through the mux
 		middleware.Recoverer,       // recover from panics without crashing server
+		auth.Middleware(db),        // resolve bearer token into the viewer on the request context
 	)
 
 	router.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -90,10 +109,16 @@ func main() {
 
 	// Create the graphql route with a Server method to handle it
 	router.Route("/graphql", func(router chi.Router) {
-		router.Handle("/", &relay.Handler{Schema: schema})
+		router.Use(loader.Middleware(db))                                                // per request batching and caching for resolvers
+		router.Handle("/", gql.MultipartHandler(schema, &relay.Handler{Schema: schema})) // multipart requests carry file uploads
 		// router.Handle("/date", &relay.Handler{Schema: schema})
 	})
 
+	// Serve images saved by the local disk store
+	if local, ok := images.(*storage.Local); ok {
+		router.Handle(storage.LocalRoute+"/*", local.Handler())
+	}
+
 	router.Route("/emailExists", func(router chi.Router) {
 		router.Post("/", func(w http.ResponseWriter, req *http.Request) {
 			api.CheckEmailExists(w, req, db)
@@ -102,15 +127,14 @@ func main() {
 
 	router.Route("/user", func(router chi.Router) {
 		router.Route("/{uid}", func(router chi.Router) {
-			pb := api.ProfileBuilder{}
 			router.Route("/upload", func(router chi.Router) {
 				router.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-					uid := chi.URLParam(req, "uid")
-					pb = api.ProfileBuilder{ID: graphql.ID(uid)}
 					w.Write(uploadTest)
 				}))
+				// Prefer the setUserPhoto mutation, kept for older clients
 				router.Handle("/send", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-					pb.UploadAnyS3(w, req, minioClient, db, "users", pb.ID)
+					uid := chi.URLParam(req, "uid")
+					api.UploadProfileImage(w, req, images, db, "users", graphql.ID(uid))
 				}))
 			})
 		})
@@ -118,17 +142,21 @@ func main() {
 
 	router.Route("/dog", func(router chi.Router) {
 		router.Route("/{dogId}", func(router chi.Router) {
-			pb := api.ProfileBuilder{}
 			router.Route("/upload", func(router chi.Router) {
 				router.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-					did := chi.URLParam(req, "dogId")
-					pb = api.ProfileBuilder{ID: graphql.ID(did)}
 					w.Write(uploadTest)
 				}))
+				// Prefer the setDogPhoto mutation, kept for older clients
 				router.Handle("/send", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-					pb.UploadAnyS3(w, req, minioClient, db, "dogs", pb.ID)
+					did := chi.URLParam(req, "dogId")
+					api.UploadProfileImage(w, req, images, db, "dogs", graphql.ID(did))
 				}))
 			})
+			// vaccination certificates, only for the dog's owner
+			router.Get("/certificates/{vaccinationId}", func(w http.ResponseWriter, req *http.Request) {
+				did, vid := chi.URLParam(req, "dogId"), chi.URLParam(req, "vaccinationId")
+				api.ServeCertificate(w, req, documents, db, graphql.ID(did), graphql.ID(vid))
+			})
 		})
 	})
 
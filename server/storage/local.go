@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalRoute is where the server mounts Local.Handler
+const LocalRoute = "/images"
+
+// Local stores images on disk, for development and running without AWS
+type Local struct {
+	Dir       string
+	PublicURL string
+}
+
+var _ ImageStore = (*Local)(nil)
+
+// NewLocal creates dir if needed and stores images inside it
+func NewLocal(dir, publicURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Local{Dir: dir, PublicURL: publicURL}, nil
+}
+
+// path resolves key inside Dir, refusing keys that would escape it
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(key))
+	if clean == string(filepath.Separator) || strings.Contains(key, "..") {
+		return "", fmt.Errorf("Error: invalid image key %q", key)
+	}
+	return filepath.Join(l.Dir, clean), nil
+}
+
+// Put writes the image to disk, replacing any previous file with the same key
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see a partial image
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := io.Copy(tmp, io.LimitReader(r, size)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get opens the image on disk
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Stat returns the size of the image on disk
+func (l *Local) Stat(ctx context.Context, key string) (int64, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Delete removes the image from disk
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the image address under the public URL base
+func (l *Local) URL(key string) string {
+	return joinURL(l.PublicURL, key)
+}
+
+// Handler serves the stored images, mount it at LocalRoute. Directories are never listed.
+func (l *Local) Handler() http.Handler {
+	return http.StripPrefix(LocalRoute, http.FileServer(filesOnly{http.Dir(l.Dir)}))
+}
+
+// filesOnly hides the directories of a file system so a file server cannot list them
+type filesOnly struct {
+	http.FileSystem
+}
+
+// Open opens name, reporting directories as missing
+func (fs filesOnly) Open(name string) (http.File, error) {
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go"
+)
+
+// S3 stores images in an S3 compatible bucket such as AWS S3 or MinIO
+type S3 struct {
+	Client    *minio.Client
+	Bucket    string
+	Prefix    string
+	PublicURL string
+}
+
+var (
+	_ ImageStore = (*S3)(nil)
+	_ Presigner  = (*S3)(nil)
+)
+
+// NewS3 creates a client for the bucket described by c
+func NewS3(c Config) (*S3, error) {
+	client, err := minio.NewWithRegion(c.Endpoint, c.AccessKey, c.SecretKey, !c.Insecure, c.Region)
+	if err != nil {
+		return nil, err
+	}
+	return &S3{Client: client, Bucket: c.Bucket, Prefix: c.Prefix, PublicURL: c.PublicURL}, nil
+}
+
+// Put uploads the image into the bucket under the configured prefix
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	_, err := s.Client.PutObjectWithContext(ctx, s.Bucket, s.Prefix+key, r, size, minio.PutObjectOptions{
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+	})
+	return err
+}
+
+// Get downloads the object from the bucket
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	// Stat first, GetObject only reports a missing key on the first read
+	if _, err := s.Stat(ctx, key); err != nil {
+		return nil, err
+	}
+	return s.Client.GetObjectWithContext(ctx, s.Bucket, s.Prefix+key, minio.GetObjectOptions{})
+}
+
+// Stat returns the size of the object in the bucket
+func (s *S3) Stat(ctx context.Context, key string) (int64, error) {
+	info, err := s.Client.StatObject(s.Bucket, s.Prefix+key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// PresignPost signs a POST policy for the object, the client sends the returned fields
+// and then the image as a multipart form
+func (s *S3) PresignPost(ctx context.Context, key string, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(s.Bucket); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetKey(s.Prefix + key); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expires)); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentLengthRange(1, maxSize); err != nil {
+		return "", nil, err
+	}
+	u, fields, err := s.Client.PresignedPostPolicy(policy)
+	if err != nil {
+		return "", nil, err
+	}
+	return u.String(), fields, nil
+}
+
+// Delete removes the image from the bucket
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.Client.RemoveObject(s.Bucket, s.Prefix+key)
+}
+
+// URL returns the image address under the public URL base, e.g. a CDN in front of the prefix
+func (s *S3) URL(key string) string {
+	return joinURL(s.PublicURL, key)
+}
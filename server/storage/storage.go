@@ -0,0 +1,132 @@
+// Package storage saves uploaded profile images to an object store and builds the
+// public URLs they are served from.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ImageStore is where profile images live. Keys are relative to the store's own
+// bucket and prefix, so callers never need to know which backend is configured.
+type ImageStore interface {
+	// Put saves size bytes from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error
+	// Get opens the object at key, returning ErrNotFound when it is missing
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat returns the size of the object at key, or ErrNotFound
+	Stat(ctx context.Context, key string) (int64, error)
+	// Delete removes the object at key, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the public address of key
+	URL(key string) string
+}
+
+// Presigner is implemented by stores that let clients upload straight to them
+type Presigner interface {
+	// PresignPost returns a URL and the form fields to POST the object at key with until
+	// expires passes. The upload is refused unless its Content-Type is contentType and it
+	// is at most maxSize bytes.
+	PresignPost(ctx context.Context, key string, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error)
+}
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("Error: image not found")
+
+// PutOptions are the headers stored alongside an object
+type PutOptions struct {
+	ContentType        string
+	ContentDisposition string
+}
+
+// Backends selectable through IMAGE_STORE
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+// Config selects and configures an ImageStore
+type Config struct {
+	Backend   string // s3 or local
+	PublicURL string // base the object key is appended to when building URLs
+
+	// S3 compatible backends, including MinIO
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string // folder inside the bucket
+	AccessKey string
+	SecretKey string
+	Insecure  bool // use http instead of https, for a local MinIO
+
+	// local backend
+	Dir string
+}
+
+// ConfigFromEnv reads the image store configuration, defaulting to the production S3 bucket
+func ConfigFromEnv() Config {
+	c := Config{
+		Backend:   getenv("IMAGE_STORE", BackendS3),
+		Endpoint:  getenv("IMAGE_S3_ENDPOINT", "s3.amazonaws.com"),
+		Region:    getenv("IMAGE_S3_REGION", "us-west-1"),
+		Bucket:    getenv("IMAGE_BUCKET", "doggy-date-app"),
+		Prefix:    getenv("IMAGE_PREFIX", "dogs/"),
+		AccessKey: os.Getenv("AWSAccessKeyId"),
+		SecretKey: os.Getenv("AWSSecretKey"),
+		Insecure:  os.Getenv("IMAGE_S3_INSECURE") == "true",
+		Dir:       getenv("IMAGE_DIR", "./uploads"),
+	}
+	defaultURL := "https://d2m79q3ctf5ck3.cloudfront.net/"
+	if c.Backend == BackendLocal {
+		defaultURL = LocalRoute + "/"
+	}
+	c.PublicURL = getenv("IMAGE_PUBLIC_URL", defaultURL)
+	return c
+}
+
+// DocumentConfigFromEnv configures the private store for documents such as vaccination
+// certificates. It shares the image credentials but keeps objects under DOCUMENT_PREFIX
+// of DOCUMENT_BUCKET, or in DOCUMENT_DIR with IMAGE_STORE=local. The server never mounts it,
+// documents are only streamed to their owners. With S3 the bucket must be set and differ
+// from the public image bucket.
+func DocumentConfigFromEnv() (Config, error) {
+	c := ConfigFromEnv()
+	public := c.Bucket
+	c.Bucket = os.Getenv("DOCUMENT_BUCKET")
+	c.Prefix = getenv("DOCUMENT_PREFIX", "private/")
+	c.Dir = getenv("DOCUMENT_DIR", "./documents")
+	c.PublicURL = ""
+	if c.Backend == BackendS3 && (c.Bucket == "" || c.Bucket == public) {
+		return Config{}, errors.New("Error: DOCUMENT_BUCKET must name a private bucket other than IMAGE_BUCKET")
+	}
+	return c, nil
+}
+
+// New creates the ImageStore described by c
+func New(c Config) (ImageStore, error) {
+	switch c.Backend {
+	case BackendS3:
+		return NewS3(c)
+	case BackendLocal:
+		return NewLocal(c.Dir, c.PublicURL)
+	default:
+		return nil, fmt.Errorf("Error: unknown IMAGE_STORE %q, expected %s or %s", c.Backend, BackendS3, BackendLocal)
+	}
+}
+
+// joinURL appends key to base with exactly one slash between them
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(key, "/")
+}
+
+func getenv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
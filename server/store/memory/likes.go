@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// likeKey is the primary key of a like, from then to
+type likeKey struct {
+	from graphql.ID
+	to   graphql.ID
+}
+
+// deleteLikes drops the likes made by or of a dog, standing in for ON DELETE CASCADE
+func (s *Store) deleteLikes(dogID graphql.ID) {
+	for k := range s.likes {
+		if k.from == dogID || k.to == dogID {
+			delete(s.likes, k)
+		}
+	}
+}
+
+// InsertLike records a like or pass between two dogs, replacing the earlier decision.
+// Repeating the same decision keeps its original time.
+func (s *Store) InsertLike(like types.Like) (types.Like, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if like.From == like.To {
+		return types.Like{}, fmt.Errorf("new row for relation likes violates check constraint")
+	}
+	if s.dogs[like.From] == nil || s.dogs[like.To] == nil {
+		return types.Like{}, fmt.Errorf("insert on likes violates foreign key constraint on dogs")
+	}
+	k := likeKey{like.From, like.To}
+	if old, ok := s.likes[k]; ok && old.Liked == like.Liked {
+		return *old, nil
+	}
+	stored := types.Like{From: like.From, To: like.To, Liked: like.Liked, CreatedAt: graphql.Time{Time: time.Now()}}
+	s.likes[k] = &stored
+	return stored, nil
+}
+
+// GetMatches returns the dogs that liked the dog back, most recently matched first
+func (s *Store) GetMatches(dogID graphql.ID) ([]types.Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matches []types.Match
+	for k, mine := range s.likes {
+		if k.from != dogID || !mine.Liked {
+			continue
+		}
+		theirs, ok := s.likes[likeKey{k.to, k.from}]
+		if !ok || !theirs.Liked {
+			continue
+		}
+		at := mine.CreatedAt
+		if theirs.CreatedAt.Time.After(at.Time) {
+			at = theirs.CreatedAt
+		}
+		matches = append(matches, types.Match{Dog: k.to, MatchedAt: at})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if !a.MatchedAt.Time.Equal(b.MatchedAt.Time) {
+			return a.MatchedAt.Time.After(b.MatchedAt.Time)
+		}
+		return a.Dog < b.Dog
+	})
+	return matches, nil
+}
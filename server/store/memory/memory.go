@@ -0,0 +1,907 @@
+// Package memory is an in-process store.Store with the same semantics as postgres.Db,
+// used to exercise resolvers and handlers without a database.
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+type user struct {
+	types.User
+	password string
+}
+
+type session struct {
+	user      graphql.ID
+	expiresAt time.Time
+}
+
+// Store keeps every table in maps guarded by a single lock
+type Store struct {
+	mu           sync.RWMutex
+	users        map[graphql.ID]*user
+	dogs         map[graphql.ID]*types.Dog
+	dates        map[graphql.ID]*types.Date
+	invitations  map[graphql.ID]*types.Invitation
+	sessions     map[string]*session
+	images       map[graphql.ID]*types.Image
+	places       map[graphql.ID]*types.Place
+	photos       map[graphql.ID]*types.DogPhoto
+	vaccinations map[graphql.ID]*types.Vaccination
+	likes        map[likeKey]*types.Like
+}
+
+// Store is an in-memory store.Store
+var _ store.Store = (*Store)(nil)
+
+// New returns an empty in-memory store
+func New() *Store {
+	return &Store{
+		users:        map[graphql.ID]*user{},
+		dogs:         map[graphql.ID]*types.Dog{},
+		dates:        map[graphql.ID]*types.Date{},
+		invitations:  map[graphql.ID]*types.Invitation{},
+		sessions:     map[string]*session{},
+		images:       map[graphql.ID]*types.Image{},
+		places:       map[graphql.ID]*types.Place{},
+		photos:       map[graphql.ID]*types.DogPhoto{},
+		vaccinations: map[graphql.ID]*types.Vaccination{},
+		likes:        map[likeKey]*types.Like{},
+	}
+}
+
+// newID generates a time based uuid like the postgres store
+func newID() graphql.ID {
+	id, _ := uuid.NewV1()
+	return graphql.ID(id.String())
+}
+
+// copyIDs returns a copy of ids so callers never share a slice with the store
+func copyIDs(ids []graphql.ID) []graphql.ID {
+	if ids == nil {
+		return nil
+	}
+	return append([]graphql.ID{}, ids...)
+}
+
+// removeID returns ids without id
+func removeID(ids []graphql.ID, id graphql.ID) []graphql.ID {
+	var out []graphql.ID
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// containsID reports whether id is in ids
+func containsID(ids []graphql.ID, id graphql.ID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// userCopy returns a copy of the user row without the password
+func (u *user) userCopy() types.User {
+	c := u.User
+	c.Dogs = copyIDs(u.Dogs)
+	c.IsAdmin = false // only selected by postgres GetSession
+	c.Home = nil      // only selected by postgres GetUsersByIDs and UpdateUser
+	return c
+}
+
+// coordsCopy returns a copy of an optional position
+func coordsCopy(c *types.Coordinates) *types.Coordinates {
+	if c == nil {
+		return nil
+	}
+	coords := *c
+	return &coords
+}
+
+// dateCopy returns a copy of a date row
+func dateCopy(d *types.Date) types.Date {
+	c := *d
+	c.Dogs = copyIDs(d.Dogs)
+	c.RequiresVaccinations = append([]string{}, d.RequiresVaccinations...)
+	c.Coordinates = coordsCopy(d.Coordinates)
+	return c
+}
+
+// userDogs returns the dogs of u in the order of its dogs array
+func (s *Store) userDogs(u *user) []types.Dog {
+	var dogs []types.Dog
+	for _, id := range u.Dogs {
+		if d, ok := s.dogs[id]; ok {
+			dogs = append(dogs, *d)
+		}
+	}
+	return dogs
+}
+
+// userByEmail finds the user with email
+func (s *Store) userByEmail(email string) *user {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+// GetUserByEmail mirrors the postgres inner join: users without dogs are not found
+func (s *Store) GetUserByEmail(email string) (types.User, []types.Dog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.userByEmail(email)
+	if u == nil {
+		return types.User{}, nil, nil
+	}
+	dogs := s.userDogs(u)
+	if len(dogs) == 0 {
+		return types.User{}, nil, nil
+	}
+	return u.userCopy(), dogs, nil
+}
+
+// GetUserByID mirrors the postgres inner join, the email is not selected
+func (s *Store) GetUserByID(id uuid.UUID) (types.User, []types.Dog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[graphql.ID(id.String())]
+	if !ok {
+		return types.User{}, nil, nil
+	}
+	dogs := s.userDogs(u)
+	if len(dogs) == 0 {
+		return types.User{}, nil, nil
+	}
+	c := u.userCopy()
+	c.Email = ""
+	return c, dogs, nil
+}
+
+// GetUsersByIDs returns the users found among userIds, without emails
+func (s *Store) GetUsersByIDs(userIds []graphql.ID) (map[graphql.ID]types.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uMap := map[graphql.ID]types.User{}
+	for _, id := range userIds {
+		if u, ok := s.users[id]; ok {
+			c := u.userCopy()
+			c.Email = ""
+			c.Home = coordsCopy(u.Home)
+			uMap[id] = c
+		}
+	}
+	return uMap, nil
+}
+
+// InsertUserDog creates a user with a first dog, emails are unique
+func (s *Store) InsertUserDog(name string, email string, passwordHash string, uImg string, dname string,
+	age int32, breed string, dImg string, profile types.DogProfile) (types.User, types.Dog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userByEmail(email) != nil {
+		return types.User{}, types.Dog{}, fmt.Errorf("duplicate key value violates unique constraint on users email")
+	}
+	uid, did := newID(), newID()
+	dog := types.Dog{ID: did, Name: dname, Age: age, Breed: breed, Owner: uid, ProfileImageURL: dImg,
+		DogProfile: insertProfile(profile)}
+	u := &user{types.User{
+		ID:              uid,
+		Name:            name,
+		Email:           email,
+		Dogs:            []graphql.ID{did},
+		ProfileImageURL: uImg,
+		JoinDate:        graphql.Time{Time: time.Now()}}, passwordHash}
+	s.users[uid] = u
+	s.dogs[did] = &dog
+	return u.userCopy(), dog, nil
+}
+
+// UpdateUser changes the non nil fields of a user
+func (s *Store) UpdateUser(id graphql.ID, name *string, email *string, img *string, home *types.Coordinates) (types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return types.User{}, sql.ErrNoRows
+	}
+	if email != nil {
+		if other := s.userByEmail(*email); other != nil && other != u {
+			return types.User{}, fmt.Errorf("duplicate key value violates unique constraint on users email")
+		}
+		u.Email = *email
+	}
+	if name != nil {
+		u.Name = *name
+	}
+	if img != nil {
+		u.ProfileImageURL = *img
+	}
+	if home != nil {
+		u.Home = coordsCopy(home)
+	}
+	c := u.userCopy()
+	c.Dogs = nil // not returned by postgres UpdateUser
+	c.Home = coordsCopy(u.Home)
+	return c, nil
+}
+
+// DeleteUser removes a user with their sessions, dogs, doggy dates and related invitations
+func (s *Store) DeleteUser(id graphql.ID) (store.Objects, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var objs store.Objects
+	for token, sess := range s.sessions {
+		if sess.user == id {
+			delete(s.sessions, token)
+		}
+	}
+	for iid, inv := range s.invitations {
+		dog, dogOK := s.dogs[inv.Dog]
+		date, dateOK := s.dates[inv.Date]
+		if (dogOK && dog.Owner == id) || (dateOK && date.User == id) {
+			delete(s.invitations, iid)
+		}
+	}
+	for did, date := range s.dates {
+		if date.User == id {
+			delete(s.dates, did)
+			continue
+		}
+		var kept []graphql.ID
+		for _, dogID := range date.Dogs {
+			if dog, ok := s.dogs[dogID]; !ok || dog.Owner != id {
+				kept = append(kept, dogID)
+			}
+		}
+		date.Dogs = kept
+	}
+	for did, dog := range s.dogs {
+		if dog.Owner == id {
+			objs.Images = append(objs.Images, s.deleteImages("dogs", did)...)
+			objs.Images = append(objs.Images, s.deleteDogPhotos(did)...)
+			objs.Certificates = append(objs.Certificates, s.deleteVaccinations(did)...)
+			s.deleteLikes(did)
+			delete(s.dogs, did)
+		}
+	}
+	objs.Images = append(objs.Images, s.deleteImages("users", id)...)
+	delete(s.users, id)
+	return objs, nil
+}
+
+// CheckEmailExists returns sql.ErrNoRows alongside false like the postgres store
+func (s *Store) CheckEmailExists(email string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.userByEmail(email) == nil {
+		return false, sql.ErrNoRows
+	}
+	return true, nil
+}
+
+// GetUserCredentials returns the id and password hash of the user with email
+func (s *Store) GetUserCredentials(email string) (graphql.ID, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.userByEmail(email)
+	if u == nil {
+		return "", "", sql.ErrNoRows
+	}
+	return u.ID, u.password, nil
+}
+
+// GetDogByID returns the dog followed by the rest of its owner's dogs, and the owner
+func (s *Store) GetDogByID(id uuid.UUID) ([]types.Dog, types.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	did := graphql.ID(id.String())
+	for _, u := range s.users {
+		if !containsID(u.Dogs, did) {
+			continue
+		}
+		dogs := []types.Dog{*s.dogs[did]}
+		for _, d := range s.userDogs(u) {
+			if d.ID != did {
+				dogs = append(dogs, d)
+			}
+		}
+		return dogs, types.User{ID: u.ID, Name: u.Name, ProfileImageURL: u.ProfileImageURL}, nil
+	}
+	return nil, types.User{}, nil
+}
+
+// GetDogsByIDs returns the dogs found among dogIds
+func (s *Store) GetDogsByIDs(dogIds []graphql.ID) (map[graphql.ID]types.Dog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dogMap := map[graphql.ID]types.Dog{}
+	for _, id := range dogIds {
+		if d, ok := s.dogs[id]; ok {
+			dogMap[id] = *d
+		}
+	}
+	return dogMap, nil
+}
+
+// GetDogsPageByOwner returns a page of the owner's dogs ordered by name then id
+func (s *Store) GetDogsPageByOwner(owner graphql.ID, page store.Page) ([]types.Dog, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var dogs []types.Dog
+	for _, d := range s.dogs {
+		if d.Owner == owner {
+			dogs = append(dogs, *d)
+		}
+	}
+	sort.Slice(dogs, func(i, j int) bool {
+		if dogs[i].Name != dogs[j].Name {
+			return dogs[i].Name < dogs[j].Name
+		}
+		return dogs[i].ID < dogs[j].ID
+	})
+	idx, more := paginate(len(dogs), page, func(i int) int {
+		return compareKey(strings.Compare(dogs[i].Name, page.Cursor.Key), dogs[i].ID, page.Cursor)
+	})
+	var out []types.Dog
+	for _, i := range idx {
+		out = append(out, dogs[i])
+	}
+	return out, more, nil
+}
+
+// GetDogOwners returns the owner of each dog found among dogIds
+func (s *Store) GetDogOwners(dogIds []graphql.ID) (map[graphql.ID]graphql.ID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	owners := map[graphql.ID]graphql.ID{}
+	for _, id := range dogIds {
+		if d, ok := s.dogs[id]; ok {
+			owners[id] = d.Owner
+		}
+	}
+	return owners, nil
+}
+
+// InsertDog adds a dog to an existing user
+func (s *Store) InsertDog(owner graphql.ID, name string, age int32, breed string, img string, profile types.DogProfile) (types.Dog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[owner]
+	if !ok {
+		return types.Dog{}, fmt.Errorf("insert on dogs violates foreign key constraint on owner")
+	}
+	dog := types.Dog{ID: newID(), Name: name, Age: age, Breed: breed, Owner: owner, ProfileImageURL: img,
+		DogProfile: insertProfile(profile)}
+	s.dogs[dog.ID] = &dog
+	u.Dogs = append(u.Dogs, dog.ID)
+	return dog, nil
+}
+
+// UpdateDog changes the non nil fields of a dog and its profile
+func (s *Store) UpdateDog(id graphql.ID, name *string, age *int32, breed *string, profile types.DogProfile) (types.Dog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.dogs[id]
+	if !ok {
+		return types.Dog{}, sql.ErrNoRows
+	}
+	if name != nil {
+		d.Name = *name
+	}
+	if age != nil {
+		d.Age = *age
+	}
+	if breed != nil {
+		d.Breed = *breed
+	}
+	// profile pointers are never written through, so replacing them keeps earlier copies intact
+	p := &d.DogProfile
+	if profile.Size != nil {
+		p.Size = profile.Size
+	}
+	if profile.WeightKg != nil {
+		p.WeightKg = profile.WeightKg
+	}
+	if profile.Sex != nil {
+		p.Sex = profile.Sex
+	}
+	if profile.Neutered != nil {
+		p.Neutered = profile.Neutered
+	}
+	if profile.EnergyLevel != nil {
+		p.EnergyLevel = profile.EnergyLevel
+	}
+	if profile.PlayStyles != nil {
+		p.PlayStyles = append([]string{}, profile.PlayStyles...)
+	}
+	if profile.GoodWithSmallDogs != nil {
+		p.GoodWithSmallDogs = profile.GoodWithSmallDogs
+	}
+	if profile.GoodWithPuppies != nil {
+		p.GoodWithPuppies = profile.GoodWithPuppies
+	}
+	if profile.GoodWithPeople != nil {
+		p.GoodWithPeople = profile.GoodWithPeople
+	}
+	return *d, nil
+}
+
+// insertProfile copies the play styles of a new dog, which default to none
+func insertProfile(p types.DogProfile) types.DogProfile {
+	p.PlayStyles = append([]string{}, p.PlayStyles...)
+	return p
+}
+
+// DeleteDog removes a dog from its owner, its doggy dates and invitations, returning the owner
+func (s *Store) DeleteDog(id graphql.ID) (graphql.ID, store.Objects, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.dogs[id]
+	if !ok {
+		return "", store.Objects{}, sql.ErrNoRows
+	}
+	count := 0
+	for _, other := range s.dogs {
+		if other.Owner == d.Owner {
+			count++
+		}
+	}
+	if count <= 1 {
+		return "", store.Objects{}, store.ErrLastDog
+	}
+	delete(s.dogs, id)
+	objs := store.Objects{Images: append(s.deleteImages("dogs", id), s.deleteDogPhotos(id)...)}
+	objs.Certificates = s.deleteVaccinations(id)
+	s.deleteLikes(id)
+	if u, ok := s.users[d.Owner]; ok {
+		u.Dogs = removeID(u.Dogs, id)
+	}
+	for iid, inv := range s.invitations {
+		if inv.Dog == id {
+			delete(s.invitations, iid)
+		}
+	}
+	for _, date := range s.dates {
+		if containsID(date.Dogs, id) {
+			date.Dogs = removeID(date.Dogs, id)
+		}
+	}
+	return d.Owner, objs, nil
+}
+
+// matches reports whether date passes every set field of f
+func (s *Store) matches(date *types.Date, f store.DateFilter) bool {
+	if f.From != nil && date.Date.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && !date.Date.Before(*f.To) {
+		return false
+	}
+	if f.Location != "" && !strings.Contains(strings.ToLower(date.Location), strings.ToLower(f.Location)) {
+		return false
+	}
+	if f.Organizer != "" && date.User != f.Organizer {
+		return false
+	}
+	if f.Dog != "" && !containsID(date.Dogs, f.Dog) {
+		return false
+	}
+	if f.Breed != "" {
+		found := false
+		for _, id := range date.Dogs {
+			if d, ok := s.dogs[id]; ok && strings.Contains(strings.ToLower(d.Breed), strings.ToLower(f.Breed)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, st := range f.Statuses {
+			if date.Status == st {
+				found = true
+				break
+			}
+		}
+		return found
+	}
+	return f.IncludeCancelled || date.Status != types.DateStatusCancelled
+}
+
+// GetDoggyDatesPage returns a page of the dates matching filter ordered by date then id
+func (s *Store) GetDoggyDatesPage(page store.Page, filter store.DateFilter) ([]types.Date, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var dates []types.Date
+	for _, d := range s.dates {
+		if s.matches(d, filter) {
+			dates = append(dates, dateCopy(d))
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		if !dates[i].Date.Equal(dates[j].Date.Time) {
+			return dates[i].Date.Before(dates[j].Date.Time)
+		}
+		return dates[i].ID < dates[j].ID
+	})
+	var cursorTime time.Time
+	if page.Cursor != nil {
+		t, err := time.Parse(time.RFC3339Nano, page.Cursor.Key)
+		if err != nil {
+			return nil, false, err
+		}
+		cursorTime = t
+	}
+	idx, more := paginate(len(dates), page, func(i int) int {
+		c := 0
+		if dates[i].Date.Before(cursorTime) {
+			c = -1
+		} else if dates[i].Date.After(cursorTime) {
+			c = 1
+		}
+		return compareKey(c, dates[i].ID, page.Cursor)
+	})
+	var out []types.Date
+	for _, i := range idx {
+		out = append(out, dates[i])
+	}
+	return out, more, nil
+}
+
+// GetDoggyDateByID returns a single doggy date
+func (s *Store) GetDoggyDateByID(id graphql.ID) (types.Date, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.dates[id]
+	if !ok {
+		return types.Date{}, sql.ErrNoRows
+	}
+	return dateCopy(d), nil
+}
+
+// GetDoggyDatesByIDs returns the doggy dates found among dateIds
+func (s *Store) GetDoggyDatesByIDs(dateIds []graphql.ID) (map[graphql.ID]types.Date, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dates := map[graphql.ID]types.Date{}
+	for _, id := range dateIds {
+		if d, ok := s.dates[id]; ok {
+			dates[id] = dateCopy(d)
+		}
+	}
+	return dates, nil
+}
+
+// GetDateOrganizer returns the user who planned a doggy date
+func (s *Store) GetDateOrganizer(id graphql.ID) (graphql.ID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.dates[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return d.User, nil
+}
+
+// InsertDoggyDate plans a new doggy date and invites each of the invited dogs
+func (s *Store) InsertDoggyDate(date types.Date, invited []graphql.ID) (types.Date, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, dogID := range invited {
+		if _, ok := s.dogs[dogID]; !ok {
+			return types.Date{}, fmt.Errorf("insert on invitations violates foreign key constraint on dogs")
+		}
+	}
+	d := dateCopy(&date)
+	d.ID = newID()
+	d.Status = types.DateStatusPlanned
+	d.CancelReason = ""
+	s.dates[d.ID] = &d
+	for _, dogID := range invited {
+		inv := &types.Invitation{
+			ID:        newID(),
+			Date:      d.ID,
+			Dog:       dogID,
+			InvitedBy: d.User,
+			Status:    types.InvitationPending,
+			CreatedAt: graphql.Time{Time: time.Now()}}
+		s.invitations[inv.ID] = inv
+	}
+	return dateCopy(&d), nil
+}
+
+// NearbyDates returns the dates matching filter within radiusKm of center, closest first
+func (s *Store) NearbyDates(center types.Coordinates, radiusKm float64, filter store.DateFilter, limit int) ([]store.NearbyDate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var dates []store.NearbyDate
+	for _, d := range s.dates {
+		if d.Coordinates == nil || !s.matches(d, filter) {
+			continue
+		}
+		if km := geo.DistanceKm(center, *d.Coordinates); km <= radiusKm {
+			dates = append(dates, store.NearbyDate{Date: dateCopy(d), DistanceKm: km})
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		if dates[i].DistanceKm != dates[j].DistanceKm {
+			return dates[i].DistanceKm < dates[j].DistanceKm
+		}
+		return dates[i].Date.ID < dates[j].Date.ID
+	})
+	if len(dates) > limit {
+		dates = dates[:limit]
+	}
+	return dates, nil
+}
+
+// UpdateDoggyDate changes the non nil fields of a doggy date
+func (s *Store) UpdateDoggyDate(id graphql.ID, date *graphql.Time, description *string, location *string, status *string) (types.Date, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.dates[id]
+	if !ok {
+		return types.Date{}, sql.ErrNoRows
+	}
+	if date != nil {
+		d.Date = *date
+	}
+	if description != nil {
+		d.Description = *description
+	}
+	if location != nil {
+		d.Location = *location
+	}
+	if status != nil {
+		d.Status = *status
+	}
+	return dateCopy(d), nil
+}
+
+// CancelDoggyDate marks a doggy date cancelled
+func (s *Store) CancelDoggyDate(id graphql.ID, reason string) (types.Date, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.dates[id]
+	if !ok {
+		return types.Date{}, sql.ErrNoRows
+	}
+	d.Status = types.DateStatusCancelled
+	d.CancelReason = reason
+	return dateCopy(d), nil
+}
+
+// DeleteDoggyDate removes a doggy date and its invitations
+func (s *Store) DeleteDoggyDate(id graphql.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for iid, inv := range s.invitations {
+		if inv.Date == id {
+			delete(s.invitations, iid)
+		}
+	}
+	delete(s.dates, id)
+	return nil
+}
+
+// InsertInvitation invites a dog to a doggy date, a dog is invited to a date at most once
+func (s *Store) InsertInvitation(dateID graphql.ID, dogID graphql.ID, invitedBy graphql.ID) (types.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, inv := range s.invitations {
+		if inv.Date == dateID && inv.Dog == dogID {
+			return types.Invitation{}, fmt.Errorf("duplicate key value violates unique constraint on invitations date_id, dog_id")
+		}
+	}
+	inv := &types.Invitation{
+		ID:        newID(),
+		Date:      dateID,
+		Dog:       dogID,
+		InvitedBy: invitedBy,
+		Status:    types.InvitationPending,
+		CreatedAt: graphql.Time{Time: time.Now()}}
+	s.invitations[inv.ID] = inv
+	return *inv, nil
+}
+
+// GetInvitationByID returns a single invitation
+func (s *Store) GetInvitationByID(id graphql.ID) (types.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	inv, ok := s.invitations[id]
+	if !ok {
+		return types.Invitation{}, sql.ErrNoRows
+	}
+	return *inv, nil
+}
+
+// sortedInvitations returns the invitations passing keep ordered by creation time
+func (s *Store) sortedInvitations(keep func(*types.Invitation) bool) []types.Invitation {
+	var invs []types.Invitation
+	for _, inv := range s.invitations {
+		if keep(inv) {
+			invs = append(invs, *inv)
+		}
+	}
+	sort.Slice(invs, func(i, j int) bool { return invs[i].CreatedAt.Before(invs[j].CreatedAt.Time) })
+	return invs
+}
+
+// GetInvitationsByDate returns every invitation sent for a doggy date
+func (s *Store) GetInvitationsByDate(dateID graphql.ID) ([]types.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedInvitations(func(inv *types.Invitation) bool { return inv.Date == dateID }), nil
+}
+
+// GetPendingInvitationsByOwner returns unanswered or maybe invitations sent to the owner's dogs
+func (s *Store) GetPendingInvitationsByOwner(owner graphql.ID) ([]types.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedInvitations(func(inv *types.Invitation) bool {
+		d, ok := s.dogs[inv.Dog]
+		return ok && d.Owner == owner && (inv.Status == types.InvitationPending || inv.Status == types.InvitationMaybe)
+	}), nil
+}
+
+// RespondToInvitation records an RSVP and adds or removes the dog from the doggy date
+func (s *Store) RespondToInvitation(id graphql.ID, status string) (types.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invitations[id]
+	if !ok {
+		return types.Invitation{}, sql.ErrNoRows
+	}
+	inv.Status = status
+	inv.RespondedAt = &graphql.Time{Time: time.Now()}
+	if d, ok := s.dates[inv.Date]; ok {
+		if status == types.InvitationAccepted {
+			if !containsID(d.Dogs, inv.Dog) {
+				d.Dogs = append(d.Dogs, inv.Dog)
+			}
+		} else {
+			d.Dogs = removeID(d.Dogs, inv.Dog)
+		}
+	}
+	return *inv, nil
+}
+
+// InsertSession stores a session token digest for a user
+func (s *Store) InsertSession(tokenHash string, u graphql.ID, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[tokenHash] = &session{u, expiresAt}
+	return nil
+}
+
+// GetSession returns the user owning an unexpired session
+func (s *Store) GetSession(tokenHash string) (types.User, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.sessions[tokenHash]
+	if !ok || !sess.expiresAt.After(time.Now()) {
+		return types.User{}, time.Time{}, sql.ErrNoRows
+	}
+	u, ok := s.users[sess.user]
+	if !ok {
+		return types.User{}, time.Time{}, sql.ErrNoRows
+	}
+	c := u.userCopy()
+	c.Dogs = nil // not selected by postgres GetSession
+	c.IsAdmin = u.IsAdmin
+	return c, sess.expiresAt, nil
+}
+
+// RotateSession swaps an unexpired session token for a new one
+func (s *Store) RotateSession(oldHash string, newHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[oldHash]
+	if !ok || !sess.expiresAt.After(time.Now()) {
+		return sql.ErrNoRows
+	}
+	delete(s.sessions, oldHash)
+	s.sessions[newHash] = &session{sess.user, expiresAt}
+	return nil
+}
+
+// DeleteSession revokes a session token
+func (s *Store) DeleteSession(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, tokenHash)
+	return nil
+}
+
+// CheckIDExists reports whether a user or dog with id exists
+func (s *Store) CheckIDExists(tableType string, id graphql.ID) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ok bool
+	switch tableType {
+	case "users":
+		_, ok = s.users[id]
+	case "dogs":
+		_, ok = s.dogs[id]
+	default:
+		return false, fmt.Errorf("relation %q does not exist", tableType)
+	}
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return true, nil
+}
+
+// deleteImages drops the image rows of an entity, standing in for ON DELETE CASCADE, and returns them
+func (s *Store) deleteImages(entityType string, id graphql.ID) []types.Image {
+	var deleted []types.Image
+	for iid, img := range s.images {
+		if img.EntityType == entityType && img.EntityID == id {
+			deleted = append(deleted, *img)
+			delete(s.images, iid)
+		}
+	}
+	return deleted
+}
+
+// ReplaceImage records img as the only image of its user or dog and updates the picture
+func (s *Store) ReplaceImage(img types.Image, imgURL string) ([]types.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch img.EntityType {
+	case "users":
+		u, ok := s.users[img.EntityID]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		u.ProfileImageURL = imgURL
+	case "dogs":
+		d, ok := s.dogs[img.EntityID]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		d.ProfileImageURL = imgURL
+	default:
+		return nil, fmt.Errorf("Error: images cannot belong to %s", img.EntityType)
+	}
+	var old []types.Image
+	exists := false
+	for iid, prev := range s.images {
+		if prev.EntityType != img.EntityType || prev.EntityID != img.EntityID {
+			continue
+		}
+		if prev.Key == img.Key {
+			exists = true
+			continue
+		}
+		old = append(old, *prev)
+		delete(s.images, iid)
+	}
+	if !exists {
+		stored := img
+		s.images[img.ID] = &stored
+	}
+	return old, nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+)
+
+// compareKey breaks a sort key comparison tie on the row id, like the postgres row comparison
+func compareKey(keyCmp int, id graphql.ID, cursor *store.Cursor) int {
+	if keyCmp != 0 {
+		return keyCmp
+	}
+	return strings.Compare(string(id), cursor.ID)
+}
+
+// paginate selects the indexes of a page from n rows sorted ascending. cmp compares row i
+// with the page cursor and is only called when the page has one. The indexes are returned
+// in page order along with whether more rows exist past the page.
+func paginate(n int, page store.Page, cmp func(i int) int) ([]int, bool) {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+		if page.Descending {
+			order[i] = n - 1 - i
+		}
+	}
+	var picked []int
+	for _, i := range order {
+		if page.Cursor != nil {
+			c := cmp(i)
+			if page.Descending {
+				c = -c
+			}
+			if (!page.Backward && c <= 0) || (page.Backward && c >= 0) {
+				continue
+			}
+		}
+		picked = append(picked, i)
+	}
+	more := len(picked) > page.Limit
+	if !more {
+		return picked, false
+	}
+	if page.Backward {
+		return picked[len(picked)-page.Limit:], true
+	}
+	return picked[:page.Limit], true
+}
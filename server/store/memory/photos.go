@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// dogPhotos returns the dog's photo rows in position order
+func (s *Store) dogPhotos(dogID graphql.ID) []*types.DogPhoto {
+	var photos []*types.DogPhoto
+	for _, p := range s.photos {
+		if p.Dog == dogID {
+			photos = append(photos, p)
+		}
+	}
+	sort.Slice(photos, func(i, j int) bool { return photos[i].Position < photos[j].Position })
+	return photos
+}
+
+// deleteDogPhotos drops the photo rows of a dog, standing in for ON DELETE CASCADE, and
+// returns their objects
+func (s *Store) deleteDogPhotos(dogID graphql.ID) []types.Image {
+	var deleted []types.Image
+	for id, p := range s.photos {
+		if p.Dog == dogID {
+			deleted = append(deleted, types.Image{Key: p.Key, Ext: p.Ext})
+			delete(s.photos, id)
+		}
+	}
+	return deleted
+}
+
+// setPrimaryPhoto moves the primary flag to photo id, or to the first photo when id is empty,
+// and copies its url to the dog's profile image
+func (s *Store) setPrimaryPhoto(dogID graphql.ID, id graphql.ID) error {
+	photos := s.dogPhotos(dogID)
+	if id == "" {
+		if len(photos) == 0 {
+			s.dogs[dogID].ProfileImageURL = ""
+			return nil
+		}
+		id = photos[0].ID
+	}
+	p, ok := s.photos[id]
+	if !ok || p.Dog != dogID {
+		return sql.ErrNoRows
+	}
+	for _, other := range photos {
+		other.Primary = other.ID == id
+	}
+	s.dogs[dogID].ProfileImageURL = p.URL
+	return nil
+}
+
+// GetDogPhotos returns the photos of each dog found among dogIds in position order
+func (s *Store) GetDogPhotos(dogIds []graphql.ID) (map[graphql.ID][]types.DogPhoto, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	photos := map[graphql.ID][]types.DogPhoto{}
+	for _, id := range dogIds {
+		for _, p := range s.dogPhotos(id) {
+			photos[id] = append(photos[id], *p)
+		}
+	}
+	return photos, nil
+}
+
+// InsertDogPhoto appends a photo to a dog's gallery, a repeated key returns the existing photo
+func (s *Store) InsertDogPhoto(photo types.DogPhoto, primary bool) (types.DogPhoto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dogs[photo.Dog]; !ok {
+		return types.DogPhoto{}, sql.ErrNoRows
+	}
+	photos := s.dogPhotos(photo.Dog)
+	var p *types.DogPhoto
+	hasPrimary := false
+	for _, existing := range photos {
+		if existing.Key == photo.Key {
+			p = existing
+		}
+		hasPrimary = hasPrimary || existing.Primary
+	}
+	if p == nil {
+		if len(photos) >= store.MaxDogPhotos {
+			return types.DogPhoto{}, store.ErrTooManyPhotos
+		}
+		p = &photo
+		p.Position = int32(len(photos))
+		p.Primary = false
+		s.photos[p.ID] = p
+		primary = primary || !hasPrimary
+	}
+	if primary && !p.Primary {
+		if err := s.setPrimaryPhoto(p.Dog, p.ID); err != nil {
+			return types.DogPhoto{}, err
+		}
+	}
+	return *p, nil
+}
+
+// DeleteDogPhoto removes a photo from a dog's gallery, promoting the first remaining photo
+// when the primary one is removed
+func (s *Store) DeleteDogPhoto(dogID graphql.ID, id graphql.ID) (types.DogPhoto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.photos[id]
+	if !ok || p.Dog != dogID {
+		return types.DogPhoto{}, sql.ErrNoRows
+	}
+	delete(s.photos, id)
+	for _, other := range s.dogPhotos(dogID) {
+		if other.Position > p.Position {
+			other.Position--
+		}
+	}
+	if p.Primary {
+		if err := s.setPrimaryPhoto(dogID, ""); err != nil {
+			return types.DogPhoto{}, err
+		}
+	}
+	return *p, nil
+}
+
+// ReorderDogPhotos positions the dog's photos in the order of ids
+func (s *Store) ReorderDogPhotos(dogID graphql.ID, ids []graphql.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	photos := s.dogPhotos(dogID)
+	seen := map[graphql.ID]bool{}
+	for _, id := range ids {
+		p, ok := s.photos[id]
+		if !ok || p.Dog != dogID || seen[id] {
+			return store.ErrPhotoOrder
+		}
+		seen[id] = true
+	}
+	if len(ids) != len(photos) {
+		return store.ErrPhotoOrder
+	}
+	for i, id := range ids {
+		s.photos[id].Position = int32(i)
+	}
+	return nil
+}
+
+// SetPrimaryDogPhoto makes a photo the dog's profile image
+func (s *Store) SetPrimaryDogPhoto(dogID graphql.ID, id graphql.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setPrimaryPhoto(dogID, id)
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// placeCopy returns a copy of a place row
+func placeCopy(p *types.Place) types.Place {
+	c := *p
+	c.Amenities = append([]string{}, p.Amenities...)
+	return c
+}
+
+// InsertPlace adds a place to the directory
+func (s *Store) InsertPlace(place types.Place) (types.Place, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := placeCopy(&place)
+	p.ID = newID()
+	s.places[p.ID] = &p
+	return placeCopy(&p), nil
+}
+
+// UpdatePlace changes the non nil fields of a place
+func (s *Store) UpdatePlace(id graphql.ID, name *string, address *string, coords *types.Coordinates, amenities *[]string) (types.Place, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.places[id]
+	if !ok {
+		return types.Place{}, sql.ErrNoRows
+	}
+	if name != nil {
+		p.Name = *name
+	}
+	if address != nil {
+		p.Address = *address
+	}
+	if coords != nil {
+		p.Coordinates = *coords
+	}
+	if amenities != nil {
+		p.Amenities = append([]string{}, *amenities...)
+	}
+	return placeCopy(p), nil
+}
+
+// DeletePlace removes a place and clears it from dates like ON DELETE SET NULL
+func (s *Store) DeletePlace(id graphql.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.places[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(s.places, id)
+	for _, d := range s.dates {
+		if d.Place == id {
+			d.Place = ""
+		}
+	}
+	return nil
+}
+
+// GetPlacesByIDs returns the places found among ids
+func (s *Store) GetPlacesByIDs(ids []graphql.ID) (map[graphql.ID]types.Place, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	places := map[graphql.ID]types.Place{}
+	for _, id := range ids {
+		if p, ok := s.places[id]; ok {
+			places[id] = placeCopy(p)
+		}
+	}
+	return places, nil
+}
+
+// SearchPlaces returns places whose name or address contains search, within radiusKm of
+// near when given, closest first or by name
+func (s *Store) SearchPlaces(near *types.Coordinates, radiusKm float64, search string, limit int) ([]store.PlaceMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	search = strings.ToLower(search)
+	var places []store.PlaceMatch
+	for _, p := range s.places {
+		if search != "" && !strings.Contains(strings.ToLower(p.Name), search) &&
+			!strings.Contains(strings.ToLower(p.Address), search) {
+			continue
+		}
+		m := store.PlaceMatch{Place: placeCopy(p)}
+		if near != nil {
+			km := geo.DistanceKm(*near, p.Coordinates)
+			if km > radiusKm {
+				continue
+			}
+			m.DistanceKm = &km
+		}
+		places = append(places, m)
+	}
+	sort.Slice(places, func(i, j int) bool {
+		a, b := places[i], places[j]
+		if near != nil && *a.DistanceKm != *b.DistanceKm {
+			return *a.DistanceKm < *b.DistanceKm
+		}
+		if near == nil && a.Place.Name != b.Place.Name {
+			return a.Place.Name < b.Place.Name
+		}
+		return a.Place.ID < b.Place.ID
+	})
+	if len(places) > limit {
+		places = places[:limit]
+	}
+	return places, nil
+}
+
+// SetAdmin grants or revokes admin rights, standing in for updating users.is_admin by hand
+func (s *Store) SetAdmin(id graphql.ID, admin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.IsAdmin = admin
+	return nil
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/geo"
+	"github.com/raymondvooo/doggy-date-app/server/store"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// GetPlaymateCandidates returns the dogs of other households living within radiusKm of near
+// that the dog has not liked or passed, closest first
+func (s *Store) GetPlaymateCandidates(dogID graphql.ID, near types.Coordinates, radiusKm float64, limit int) ([]store.Playmate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dog, ok := s.dogs[dogID]
+	if !ok {
+		return nil, nil
+	}
+	var playmates []store.Playmate
+	distances := map[graphql.ID]float64{}
+	for _, d := range s.dogs {
+		u, ok := s.users[d.Owner]
+		if !ok || u.Home == nil || d.Owner == dog.Owner || s.likes[likeKey{dogID, d.ID}] != nil {
+			continue
+		}
+		km := geo.DistanceKm(near, *u.Home)
+		if km > radiusKm {
+			continue
+		}
+		distances[d.ID] = km
+		playmates = append(playmates, store.Playmate{Dog: *d, Home: *u.Home})
+	}
+	sort.Slice(playmates, func(i, j int) bool {
+		a, b := playmates[i].Dog.ID, playmates[j].Dog.ID
+		if distances[a] != distances[b] {
+			return distances[a] < distances[b]
+		}
+		return a < b
+	})
+	if len(playmates) > limit {
+		playmates = playmates[:limit]
+	}
+	return playmates, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// dogVaccinations returns the dog's records ordered by when they were administered
+func (s *Store) dogVaccinations(dogID graphql.ID) []types.Vaccination {
+	var records []types.Vaccination
+	for _, v := range s.vaccinations {
+		if v.Dog == dogID {
+			records = append(records, *v)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i].AdministeredOn.Time, records[j].AdministeredOn.Time
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// deleteVaccinations drops the records of a dog, standing in for ON DELETE CASCADE, and
+// returns the keys of their certificates
+func (s *Store) deleteVaccinations(dogID graphql.ID) []string {
+	var certificates []string
+	for id, v := range s.vaccinations {
+		if v.Dog == dogID {
+			if v.CertificateKey != "" {
+				certificates = append(certificates, v.CertificateKey)
+			}
+			delete(s.vaccinations, id)
+		}
+	}
+	return certificates
+}
+
+// GetVaccinations returns the records of each dog found among dogIds
+func (s *Store) GetVaccinations(dogIds []graphql.ID) (map[graphql.ID][]types.Vaccination, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	records := map[graphql.ID][]types.Vaccination{}
+	for _, id := range dogIds {
+		if r := s.dogVaccinations(id); len(r) > 0 {
+			records[id] = r
+		}
+	}
+	return records, nil
+}
+
+// InsertVaccination adds a record to an existing dog
+func (s *Store) InsertVaccination(v types.Vaccination) (types.Vaccination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dogs[v.Dog]; !ok {
+		return types.Vaccination{}, sql.ErrNoRows
+	}
+	stored := v
+	s.vaccinations[v.ID] = &stored
+	return v, nil
+}
+
+// DeleteVaccination removes one of a dog's records
+func (s *Store) DeleteVaccination(dogID graphql.ID, id graphql.ID) (types.Vaccination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.vaccinations[id]
+	if !ok || v.Dog != dogID {
+		return types.Vaccination{}, sql.ErrNoRows
+	}
+	delete(s.vaccinations, id)
+	return *v, nil
+}
@@ -0,0 +1,175 @@
+// Package store defines the persistence interface used by the graphql resolvers, the REST
+// handlers and the auth middleware. postgres.Db is the production implementation and
+// memory.Store keeps everything in process for tests and local development.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// MaxDogPhotos caps the size of a dog's gallery, see InsertDogPhoto
+const MaxDogPhotos = 10
+
+var (
+	// ErrTooManyPhotos is returned by InsertDogPhoto when the dog's gallery is full
+	ErrTooManyPhotos = fmt.Errorf("Error: A dog can have at most %d photos", MaxDogPhotos)
+	// ErrLastDog is returned by DeleteDog when the dog is its owner's only one
+	ErrLastDog = errors.New("Error: A user must keep at least one dog")
+	// ErrPhotoOrder is returned by ReorderDogPhotos when ids do not list each of the dog's photos once
+	ErrPhotoOrder = errors.New("Error: photoIds must list each of the dog's photos once")
+)
+
+// Store is implemented by every backend holding users, dogs, doggy dates, sessions and invitations.
+// Lookups of missing rows return sql.ErrNoRows, matching database/sql.
+type Store interface {
+	// Users
+	GetUserByEmail(email string) (types.User, []types.Dog, error)
+	GetUserByID(id uuid.UUID) (types.User, []types.Dog, error)
+	GetUsersByIDs(userIds []graphql.ID) (map[graphql.ID]types.User, error)
+	InsertUserDog(name string, email string, passwordHash string, uImg string, dname string,
+		age int32, breed string, dImg string, profile types.DogProfile) (types.User, types.Dog, error)
+	// UpdateUser keeps the current value of nil arguments, home is only selected by UpdateUser and GetUsersByIDs
+	UpdateUser(id graphql.ID, name *string, email *string, img *string, home *types.Coordinates) (types.User, error)
+	// DeleteUser removes the user with their dogs and doggy dates, returning the stored objects
+	// of the removed rows so they can be deleted
+	DeleteUser(id graphql.ID) (Objects, error)
+	CheckEmailExists(email string) (bool, error)
+	GetUserCredentials(email string) (graphql.ID, string, error)
+
+	// Dogs
+	GetDogByID(id uuid.UUID) ([]types.Dog, types.User, error)
+	GetDogsByIDs(dogIds []graphql.ID) (map[graphql.ID]types.Dog, error)
+	GetDogsPageByOwner(owner graphql.ID, page Page) ([]types.Dog, bool, error)
+	GetDogOwners(dogIds []graphql.ID) (map[graphql.ID]graphql.ID, error)
+	InsertDog(owner graphql.ID, name string, age int32, breed string, img string, profile types.DogProfile) (types.Dog, error)
+	// UpdateDog keeps the current value of nil arguments and nil profile fields
+	UpdateDog(id graphql.ID, name *string, age *int32, breed *string, profile types.DogProfile) (types.Dog, error)
+	// DeleteDog returns the dog's owner and the stored objects of the removed rows, refusing
+	// with ErrLastDog to delete the owner's only dog
+	DeleteDog(id graphql.ID) (graphql.ID, Objects, error)
+	// GetPlaymateCandidates returns the closest limit dogs of other households than the dog's whose
+	// home is within radiusKm of near, leaving out dogs it has already liked or passed
+	GetPlaymateCandidates(dogID graphql.ID, near types.Coordinates, radiusKm float64, limit int) ([]Playmate, error)
+
+	// Likes
+	// InsertLike records a like or pass, replacing the dog's earlier decision about the same dog
+	InsertLike(like types.Like) (types.Like, error)
+	// GetMatches returns the dogs that liked the dog back, most recently matched first
+	GetMatches(dogID graphql.ID) ([]types.Match, error)
+
+	// Dog photos, the primary photo's URL is kept as the dog's profile image
+	GetDogPhotos(dogIds []graphql.ID) (map[graphql.ID][]types.DogPhoto, error)
+	// InsertDogPhoto appends a photo to the gallery, returning the existing photo when the key is
+	// already there. It becomes primary when primary is set or the dog has no primary photo.
+	// A new photo is refused with ErrTooManyPhotos once the dog has MaxDogPhotos.
+	InsertDogPhoto(photo types.DogPhoto, primary bool) (types.DogPhoto, error)
+	// DeleteDogPhoto removes a photo and closes the gap it leaves, promoting the first remaining
+	// photo when the primary one is removed
+	DeleteDogPhoto(dogID graphql.ID, id graphql.ID) (types.DogPhoto, error)
+	// ReorderDogPhotos positions the dog's photos in the order of ids, which must list each once
+	ReorderDogPhotos(dogID graphql.ID, ids []graphql.ID) error
+	SetPrimaryDogPhoto(dogID graphql.ID, id graphql.ID) error
+
+	// Vaccinations
+	GetVaccinations(dogIds []graphql.ID) (map[graphql.ID][]types.Vaccination, error)
+	InsertVaccination(v types.Vaccination) (types.Vaccination, error)
+	DeleteVaccination(dogID graphql.ID, id graphql.ID) (types.Vaccination, error)
+
+	// Doggy dates
+	GetDoggyDatesPage(page Page, filter DateFilter) ([]types.Date, bool, error)
+	GetDoggyDateByID(id graphql.ID) (types.Date, error)
+	GetDoggyDatesByIDs(dateIds []graphql.ID) (map[graphql.ID]types.Date, error)
+	GetDateOrganizer(id graphql.ID) (graphql.ID, error)
+	// InsertDoggyDate plans the date and sends a pending invitation to each invited dog, all or nothing
+	InsertDoggyDate(date types.Date, invited []graphql.ID) (types.Date, error)
+	NearbyDates(center types.Coordinates, radiusKm float64, filter DateFilter, limit int) ([]NearbyDate, error)
+	UpdateDoggyDate(id graphql.ID, date *graphql.Time, description *string, location *string, status *string) (types.Date, error)
+	CancelDoggyDate(id graphql.ID, reason string) (types.Date, error)
+	DeleteDoggyDate(id graphql.ID) error
+
+	// Invitations
+	InsertInvitation(dateID graphql.ID, dogID graphql.ID, invitedBy graphql.ID) (types.Invitation, error)
+	GetInvitationByID(id graphql.ID) (types.Invitation, error)
+	GetInvitationsByDate(dateID graphql.ID) ([]types.Invitation, error)
+	GetPendingInvitationsByOwner(owner graphql.ID) ([]types.Invitation, error)
+	RespondToInvitation(id graphql.ID, status string) (types.Invitation, error)
+
+	// Places
+	InsertPlace(place types.Place) (types.Place, error)
+	UpdatePlace(id graphql.ID, name *string, address *string, coords *types.Coordinates, amenities *[]string) (types.Place, error)
+	DeletePlace(id graphql.ID) error
+	GetPlacesByIDs(ids []graphql.ID) (map[graphql.ID]types.Place, error)
+	SearchPlaces(near *types.Coordinates, radiusKm float64, search string, limit int) ([]PlaceMatch, error)
+
+	// Sessions
+	InsertSession(tokenHash string, user graphql.ID, expiresAt time.Time) error
+	GetSession(tokenHash string) (types.User, time.Time, error)
+	RotateSession(oldHash string, newHash string, expiresAt time.Time) error
+	DeleteSession(tokenHash string) error
+
+	// Shared by users and dogs, tableType is "users" or "dogs"
+	CheckIDExists(tableType string, id graphql.ID) (bool, error)
+	// ReplaceImage records img as the entity's only image and sets profile_image to imgURL,
+	// returning the images it replaced so their objects can be deleted
+	ReplaceImage(img types.Image, imgURL string) ([]types.Image, error)
+}
+
+// Objects lists what deleted rows kept in storage, the rows are gone so only the keys remain
+type Objects struct {
+	// Images holds profile images and gallery photos, stored in every size, see images.Delete
+	Images []types.Image
+	// Certificates are keys in the private document store
+	Certificates []string
+}
+
+// Cursor is the keyset position of a row: its sort key and id
+type Cursor struct {
+	Key string
+	ID  string
+}
+
+// Page selects up to Limit rows after Cursor, or before it when Backward is set.
+// A nil Cursor starts from the first (or last) row. Descending reverses the sort order.
+type Page struct {
+	Limit      int
+	Backward   bool
+	Descending bool
+	Cursor     *Cursor
+}
+
+// DateFilter narrows the doggy dates returned by GetDoggyDatesPage, zero fields are ignored.
+// Cancelled dates are left out unless Statuses asks for them or IncludeCancelled is set.
+type DateFilter struct {
+	From             *time.Time
+	To               *time.Time
+	Location         string
+	Organizer        graphql.ID
+	Dog              graphql.ID
+	Breed            string
+	Statuses         []string
+	IncludeCancelled bool
+}
+
+// NearbyDate is a doggy date with its great-circle distance from the search center
+type NearbyDate struct {
+	Date       types.Date
+	DistanceKm float64
+}
+
+// PlaceMatch is a place found by SearchPlaces, DistanceKm is set when searching near a point
+type PlaceMatch struct {
+	Place      types.Place
+	DistanceKm *float64
+}
+
+// Playmate is a dog found by GetPlaymateCandidates with where its household is
+type Playmate struct {
+	Dog  types.Dog
+	Home types.Coordinates
+}
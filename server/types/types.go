@@ -11,6 +11,8 @@ type User struct {
 	Dogs            []graphql.ID
 	ProfileImageURL string
 	JoinDate        graphql.Time
+	IsAdmin         bool
+	Home            *Coordinates // where the household is, nil until the user sets it
 }
 
 type Dog struct {
@@ -20,13 +22,159 @@ type Dog struct {
 	Breed           string
 	Owner           graphql.ID
 	ProfileImageURL string
+	DogProfile
 }
 
+// DogProfile describes a dog to help decide which dogs should meet, nil fields are unknown
+type DogProfile struct {
+	Size              *string
+	WeightKg          *float64
+	Sex               *string
+	Neutered          *bool
+	EnergyLevel       *string
+	PlayStyles        []string
+	GoodWithSmallDogs *bool
+	GoodWithPuppies   *bool
+	GoodWithPeople    *bool
+}
+
+// DogSize enum values from smallest to largest, stored as is in dogs.size
+var DogSizes = []string{"TOY", "SMALL", "MEDIUM", "LARGE", "GIANT"}
+
+// DogSex enum values, stored as is in dogs.sex
+const (
+	DogMale   = "MALE"
+	DogFemale = "FEMALE"
+)
+
+// EnergyLevel enum values from calmest to most active, stored as is in dogs.energy_level
+var EnergyLevels = []string{"LOW", "MEDIUM", "HIGH"}
+
+// PlayStyle enum values, stored as is in dogs.play_styles
+const (
+	PlayChase   = "CHASE"
+	PlayWrestle = "WRESTLE"
+	PlayFetch   = "FETCH"
+	PlayTug     = "TUG"
+	PlaySniff   = "SNIFF"
+	PlayGentle  = "GENTLE"
+)
+
 type Date struct {
+	ID           graphql.ID
+	Date         graphql.Time
+	Description  string
+	Dogs         []graphql.ID
+	Location     string
+	User         graphql.ID
+	Status       string
+	CancelReason string
+	Coordinates  *Coordinates // nil when the date has no map position
+	PlaceName    string
+	Place        graphql.ID // empty when the date is not at a directory place
+	// RequiresVaccinations lists the vaccines each dog must have current on the day of the date
+	RequiresVaccinations []string
+}
+
+// Coordinates is a position in degrees
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// DoggyDateStatus enum values, stored as is in doggy_dates.status
+const (
+	DateStatusPlanned   = "PLANNED"
+	DateStatusConfirmed = "CONFIRMED"
+	DateStatusCancelled = "CANCELLED"
+	DateStatusCompleted = "COMPLETED"
+)
+
+type Invitation struct {
+	ID          graphql.ID
+	Date        graphql.ID
+	Dog         graphql.ID
+	InvitedBy   graphql.ID
+	Status      string
+	CreatedAt   graphql.Time
+	RespondedAt *graphql.Time
+}
+
+// InvitationStatus enum values, stored as is in invitations.status
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationDeclined = "DECLINED"
+	InvitationMaybe    = "MAYBE"
+)
+
+// Image is an uploaded picture of a user or dog, EntityType is "users" or "dogs"
+type Image struct {
+	ID          graphql.ID
+	EntityType  string
+	EntityID    graphql.ID
+	Key         string
+	Ext         string
+	ContentType string
+	CreatedAt   graphql.Time
+}
+
+// DogPhoto is a picture in a dog's gallery, stored like an Image. URL is the full size URL
+type DogPhoto struct {
 	ID          graphql.ID
-	Date        graphql.Time
-	Description string
-	Dogs        []graphql.ID
-	Location    string
-	User        graphql.ID
+	Dog         graphql.ID
+	Key         string
+	Ext         string
+	ContentType string
+	URL         string
+	Position    int32
+	Primary     bool
+	CreatedAt   graphql.Time
 }
+
+// Vaccination is a dog's vaccine record. CertificateKey is the key of an uploaded certificate
+// in the private document store, empty when there is none
+type Vaccination struct {
+	ID                     graphql.ID
+	Dog                    graphql.ID
+	Vaccine                string
+	AdministeredOn         graphql.Time
+	ExpiresOn              *graphql.Time // nil when the vaccine does not expire
+	CertificateKey         string
+	CertificateContentType string
+	CreatedAt              graphql.Time
+}
+
+// VaccineType enum values, stored as is in vaccinations.vaccine
+var VaccineTypes = []string{"RABIES", "DHPP", "BORDETELLA", "LEPTOSPIROSIS", "CANINE_INFLUENZA", "LYME"}
+
+// Like is a dog's like or pass of another dog, Liked is false for a pass
+type Like struct {
+	From      graphql.ID
+	To        graphql.ID
+	Liked     bool
+	CreatedAt graphql.Time
+}
+
+// Match is a mutual like seen from one of the dogs, Dog is the other dog. MatchedAt is when
+// the second of the two likes was made.
+type Match struct {
+	Dog       graphql.ID
+	MatchedAt graphql.Time
+}
+
+// Place is a dog park or venue in the places directory
+type Place struct {
+	ID          graphql.ID
+	Name        string
+	Address     string
+	Coordinates Coordinates
+	Amenities   []string
+}
+
+// Amenity enum values, stored as is in places.amenities
+const (
+	AmenityOffLeash = "OFF_LEASH"
+	AmenityFenced   = "FENCED"
+	AmenityWater    = "WATER"
+)
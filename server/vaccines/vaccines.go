@@ -0,0 +1,104 @@
+// Package vaccines decides whether a dog's vaccination records are up to date.
+package vaccines
+
+import (
+	"sort"
+	"time"
+
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// ExpiringWithin is how close to its expiry a vaccination counts as expiring
+const ExpiringWithin = 30 * 24 * time.Hour
+
+// VaccinationStatus enum values
+const (
+	StatusCurrent  = "CURRENT"
+	StatusExpiring = "EXPIRING"
+	StatusExpired  = "EXPIRED"
+)
+
+// Valid reports whether a record protects the dog at t. A record without an expiry
+// never expires, one with an expiry lapses at the start of that day.
+func Valid(v types.Vaccination, t time.Time) bool {
+	if v.AdministeredOn.Time.After(t) {
+		return false
+	}
+	return v.ExpiresOn == nil || t.Before(v.ExpiresOn.Time)
+}
+
+// RecordStatus is the status of a single record at now
+func RecordStatus(v types.Vaccination, now time.Time) string {
+	switch {
+	case !Valid(v, now):
+		return StatusExpired
+	case v.ExpiresOn != nil && v.ExpiresOn.Time.Sub(now) <= ExpiringWithin:
+		return StatusExpiring
+	default:
+		return StatusCurrent
+	}
+}
+
+// Status summarizes a dog's records at now using the best record of each vaccine: expired
+// if any vaccine has lapsed, expiring if any lapses soon and current otherwise. A dog
+// without records has no status.
+func Status(records []types.Vaccination, now time.Time) *string {
+	if len(records) == 0 {
+		return nil
+	}
+	status := StatusCurrent
+	for _, v := range latest(records) {
+		switch RecordStatus(v, now) {
+		case StatusExpired:
+			status = StatusExpired
+		case StatusExpiring:
+			if status == StatusCurrent {
+				status = StatusExpiring
+			}
+		}
+	}
+	return &status
+}
+
+// Missing returns the required vaccines the records do not cover at t, in the order required
+func Missing(records []types.Vaccination, required []string, t time.Time) []string {
+	missing := []string{}
+	for _, vaccine := range required {
+		covered := false
+		for _, v := range records {
+			if v.Vaccine == vaccine && Valid(v, t) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, vaccine)
+		}
+	}
+	return missing
+}
+
+// latest keeps the record of each vaccine that expires last, a record without expiry wins
+func latest(records []types.Vaccination) []types.Vaccination {
+	best := map[string]types.Vaccination{}
+	for _, v := range records {
+		b, ok := best[v.Vaccine]
+		if !ok || expiresAfter(v, b) {
+			best[v.Vaccine] = v
+		}
+	}
+	var out []types.Vaccination
+	for _, v := range best {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Vaccine < out[j].Vaccine })
+	return out
+}
+
+// expiresAfter reports whether a protects for longer than b
+func expiresAfter(a, b types.Vaccination) bool {
+	if a.ExpiresOn == nil || b.ExpiresOn == nil {
+		return a.ExpiresOn == nil && b.ExpiresOn != nil
+	}
+	return a.ExpiresOn.Time.After(b.ExpiresOn.Time)
+}
@@ -0,0 +1,150 @@
+package vaccines
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/raymondvooo/doggy-date-app/server/types"
+)
+
+// day parses a date the way vaccinations store them, at midnight UTC
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// record is a vaccination given on administered that expires on expires, or never when empty
+func record(vaccine string, administered string, expires string) types.Vaccination {
+	v := types.Vaccination{Vaccine: vaccine, AdministeredOn: graphql.Time{Time: day(administered)}}
+	if expires != "" {
+		v.ExpiresOn = &graphql.Time{Time: day(expires)}
+	}
+	return v
+}
+
+func TestValid(t *testing.T) {
+	rabies := record("RABIES", "2026-01-10", "2027-01-10")
+	tests := []struct {
+		name string
+		v    types.Vaccination
+		at   time.Time
+		want bool
+	}{
+		{"before administered", rabies, day("2026-01-09"), false},
+		{"on administered day", rabies, day("2026-01-10"), true},
+		{"last moment before expiry", rabies, day("2027-01-10").Add(-time.Nanosecond), true},
+		{"at the start of the expiry day", rabies, day("2027-01-10"), false},
+		{"during the expiry day", rabies, day("2027-01-10").Add(10 * time.Hour), false},
+		{"no expiry", record("DHPP", "2020-05-01", ""), day("2040-01-01"), true},
+		{"no expiry before administered", record("DHPP", "2020-05-01", ""), day("2020-04-30"), false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.v, tt.at); got != tt.want {
+			t.Errorf("%s: Valid = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	now := day("2026-06-01")
+	tests := []struct {
+		name    string
+		records []types.Vaccination
+		want    string // empty for no status
+	}{
+		{"no records", nil, ""},
+		{"current", []types.Vaccination{record("RABIES", "2026-01-10", "2027-01-10")}, StatusCurrent},
+		{"no expiry", []types.Vaccination{record("RABIES", "2026-01-10", "")}, StatusCurrent},
+		{"expired", []types.Vaccination{record("RABIES", "2025-01-10", "2026-01-10")}, StatusExpired},
+		{"expires today", []types.Vaccination{record("RABIES", "2025-06-01", "2026-06-01")}, StatusExpired},
+		{"exactly 30 days left", []types.Vaccination{record("RABIES", "2025-07-01", "2026-07-01")}, StatusExpiring},
+		{"31 days left", []types.Vaccination{record("RABIES", "2025-07-02", "2026-07-02")}, StatusCurrent},
+		{"lapsed with a newer current one", []types.Vaccination{
+			record("RABIES", "2025-01-10", "2026-01-10"),
+			record("RABIES", "2026-01-10", "2027-01-10"),
+		}, StatusCurrent},
+		{"lapsed with a newer one without expiry", []types.Vaccination{
+			record("RABIES", "2026-01-10", ""),
+			record("RABIES", "2025-01-10", "2026-01-10"),
+		}, StatusCurrent},
+		{"one vaccine lapsed", []types.Vaccination{
+			record("RABIES", "2026-01-10", "2027-01-10"),
+			record("DHPP", "2025-01-10", "2026-01-10"),
+		}, StatusExpired},
+		{"one vaccine expiring", []types.Vaccination{
+			record("RABIES", "2026-01-10", "2027-01-10"),
+			record("DHPP", "2025-06-10", "2026-06-10"),
+		}, StatusExpiring},
+		{"expired beats expiring", []types.Vaccination{
+			record("DHPP", "2025-06-10", "2026-06-10"),
+			record("LYME", "2025-01-10", "2026-01-10"),
+		}, StatusExpired},
+	}
+	for _, tt := range tests {
+		got := Status(tt.records, now)
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("%s: Status = %q, want none", tt.name, *got)
+			}
+			continue
+		}
+		if got == nil || *got != tt.want {
+			t.Errorf("%s: Status = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExpiringBoundary(t *testing.T) {
+	v := record("RABIES", "2026-01-10", "2027-01-10")
+	expires := v.ExpiresOn.Time
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{expires.Add(-ExpiringWithin - time.Nanosecond), StatusCurrent},
+		{expires.Add(-ExpiringWithin), StatusExpiring},
+		{expires.Add(-time.Nanosecond), StatusExpiring},
+		{expires, StatusExpired},
+	}
+	for _, tt := range tests {
+		if got := RecordStatus(v, tt.now); got != tt.want {
+			t.Errorf("RecordStatus at %s = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	records := []types.Vaccination{
+		record("RABIES", "2025-01-10", "2026-01-10"),
+		record("RABIES", "2026-01-10", "2027-01-10"),
+		record("DHPP", "2024-03-01", ""),
+		record("BORDETELLA", "2025-08-01", "2026-08-01"),
+	}
+	required := []string{"BORDETELLA", "RABIES", "LYME", "DHPP"}
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{"all but one covered", day("2026-06-01"), []string{"LYME"}},
+		{"on the day bordetella expires", day("2026-08-01"), []string{"BORDETELLA", "LYME"}},
+		{"the day before it expires", day("2026-07-31"), []string{"LYME"}},
+		{"after every expiry", day("2027-02-01"), []string{"BORDETELLA", "RABIES", "LYME"}},
+		{"just before the first rabies shot lapses", day("2026-01-10").Add(-time.Nanosecond), []string{"LYME"}},
+		{"as the second rabies shot takes over", day("2026-01-10"), []string{"LYME"}},
+		{"before any shot", day("2024-01-01"), []string{"BORDETELLA", "RABIES", "LYME", "DHPP"}},
+	}
+	for _, tt := range tests {
+		if got := Missing(records, required, tt.at); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Missing = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := Missing(records, nil, day("2026-06-01")); got == nil || len(got) != 0 {
+		t.Errorf("Missing with nothing required = %#v, want empty", got)
+	}
+}